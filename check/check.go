@@ -0,0 +1,107 @@
+// Package check implements the check expressions of user defined columns.
+//
+// An expression is a list of conditions on the column separated by ";",
+// every condition has the form "<operator> <value>" and all of them must
+// hold:
+//
+//	>= 0; <= 100
+//	in low, medium, high
+package check
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	Equal          = "=="
+	NotEqual       = "!="
+	Greater        = ">"
+	GreaterOrEqual = ">="
+	Less           = "<"
+	LessOrEqual    = "<="
+	In             = "in"
+)
+
+// operators maps the operators to SQL.
+var operators = map[string]string{
+	Equal:          "=",
+	NotEqual:       "<>",
+	Greater:        ">",
+	GreaterOrEqual: ">=",
+	Less:           "<",
+	LessOrEqual:    "<=",
+}
+
+type Condition struct {
+	Operator string
+	Values   []string
+}
+
+// Parse splits an expression into its conditions. The values of "in" are
+// separated by ",".
+func Parse(expression string) ([]Condition, error) {
+	conditions := []Condition{}
+
+	for part := range strings.SplitSeq(expression, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		operator, value, ok := strings.Cut(part, " ")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid condition %q, expected \"<operator> <value>\"", part)
+		}
+
+		condition := Condition{Operator: operator}
+		switch {
+		case operator == In:
+			for item := range strings.SplitSeq(value, ",") {
+				item = strings.TrimSpace(item)
+				if item == "" {
+					return nil, fmt.Errorf("empty value in condition %q", part)
+				}
+				condition.Values = append(condition.Values, item)
+			}
+		case operators[operator] != "":
+			condition.Values = []string{value}
+		default:
+			return nil, fmt.Errorf("unknown operator %q in condition %q", operator, part)
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("empty check expression")
+	}
+	return conditions, nil
+}
+
+// SQL renders the conditions as a boolean expression on column, an already
+// quoted identifier. literal renders a value as an SQL literal of the column
+// type and rejects values that don't fit it.
+func SQL(conditions []Condition, column string, literal func(value string) (string, error)) (string, error) {
+	parts := []string{}
+
+	for _, condition := range conditions {
+		literals := make([]string, len(condition.Values))
+		for i, value := range condition.Values {
+			l, err := literal(value)
+			if err != nil {
+				return "", fmt.Errorf("invalid value %q: %v", value, err)
+			}
+			literals[i] = l
+		}
+
+		if condition.Operator == In {
+			parts = append(parts, fmt.Sprintf("%s IN (%s)", column, strings.Join(literals, ", ")))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s %s %s", column, operators[condition.Operator], literals[0]))
+		}
+	}
+
+	return strings.Join(parts, " AND "), nil
+}
@@ -0,0 +1,57 @@
+package check
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	conditions, err := Parse(">= 0; <= 100; in 1, 2 ,3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Condition{
+		{Operator: GreaterOrEqual, Values: []string{"0"}},
+		{Operator: LessOrEqual, Values: []string{"100"}},
+		{Operator: In, Values: []string{"1", "2", "3"}},
+	}
+	if !reflect.DeepEqual(conditions, want) {
+		t.Fatalf("got %+v, want %+v", conditions, want)
+	}
+
+	for _, expression := range []string{"", " ; ", ">=", "~ 1", "in a,,b"} {
+		if _, err := Parse(expression); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expression)
+		}
+	}
+}
+
+func TestSQL(t *testing.T) {
+	conditions, err := Parse("> 0; != 5; in a, b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	quote := func(value string) (string, error) {
+		return fmt.Sprintf("'%s'", value), nil
+	}
+
+	got, err := SQL(conditions, `"score"`, quote)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `"score" > '0' AND "score" <> '5' AND "score" IN ('a', 'b')`
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	reject := func(value string) (string, error) {
+		return "", fmt.Errorf("not a number")
+	}
+	if _, err := SQL(conditions, `"score"`, reject); err == nil {
+		t.Fatal("SQL accepted a value rejected by literal")
+	}
+}
@@ -0,0 +1,387 @@
+// Package config loads the settings of the server. Values come from, in
+// increasing order of precedence, the defaults, an optional YAML file, the
+// environment and the command line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	// Addr is the address the HTTP server listens on.
+	Addr string `yaml:"addr"`
+	// CORSOrigins are the browser origins allowed to call the API and open
+	// a WebSocket.
+	CORSOrigins []string `yaml:"cors_origins"`
+	// RequestTimeout bounds every API request, 0 disables it.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// ShutdownTimeout bounds the wait for requests and jobs on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	Database  Database  `yaml:"database"`
+	Discord   Discord   `yaml:"discord"`
+	WebSocket WebSocket `yaml:"websocket"`
+	Scheduler Scheduler `yaml:"scheduler"`
+	Tracing   Tracing   `yaml:"tracing"`
+	Log       Log       `yaml:"log"`
+
+	LogRetention LogRetention `yaml:"log_retention"`
+}
+
+type Database struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+	// MigrateOnStart applies the pending migrations before serving.
+	MigrateOnStart bool `yaml:"migrate_on_start"`
+}
+
+// DSN returns the connection string of lib/pq.
+func (d Database) DSN() string {
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Name, d.Password, d.SSLMode,
+	)
+}
+
+type Discord struct {
+	// Token is optional, without it Discord is reported as not configured
+	// and the rest of the server runs.
+	Token string `yaml:"token"`
+}
+
+type WebSocket struct {
+	// Backplane relays WebSocket messages between instances, postgres or
+	// none.
+	Backplane string `yaml:"backplane"`
+}
+
+type Scheduler struct {
+	// JobTimeout bounds a single run of a scheduled message, 0 disables it.
+	JobTimeout time.Duration `yaml:"job_timeout"`
+}
+
+type Tracing struct {
+	// OTLPEndpoint is the URL of the OTLP/HTTP collector the spans are
+	// exported to, such as http://localhost:4318. Empty disables the export.
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+	// Stdout also writes the spans to stdout, for local debugging.
+	Stdout bool `yaml:"stdout"`
+	// SampleRatio is the share of the traces recorded, from 0 to 1.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type Log struct {
+	// Level is the minimum level written: debug, info, warn or error.
+	Level string `yaml:"level"`
+	// Format is json, or text for reading in a terminal.
+	Format string `yaml:"format"`
+}
+
+// LogRetention is how long the logs of the scheduled messages are kept, per
+// level. An age of 0 keeps the logs of the level forever.
+type LogRetention struct {
+	// Interval is the time between two prunes.
+	Interval time.Duration `yaml:"interval"`
+	Info     time.Duration `yaml:"info"`
+	Warn     time.Duration `yaml:"warn"`
+	Error    time.Duration `yaml:"error"`
+}
+
+const (
+	BackplanePostgres = "postgres"
+	BackplaneNone     = "none"
+)
+
+var (
+	logLevels  = []string{"debug", "info", "warn", "error"}
+	logFormats = []string{"json", "text"}
+)
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Default returns the settings used when nothing overrides them.
+func Default() *Config {
+	return &Config{
+		Addr:            ":8080",
+		CORSOrigins:     []string{"http://localhost:5173"},
+		RequestTimeout:  30 * time.Second,
+		ShutdownTimeout: 10 * time.Second,
+		Database: Database{
+			Port:           "5432",
+			SSLMode:        "disable",
+			MigrateOnStart: true,
+		},
+		WebSocket: WebSocket{Backplane: BackplanePostgres},
+		Scheduler: Scheduler{JobTimeout: 5 * time.Minute},
+		Tracing:   Tracing{SampleRatio: 1},
+		Log:       Log{Level: "info", Format: "json"},
+		LogRetention: LogRetention{
+			Interval: time.Hour,
+			Info:     7 * 24 * time.Hour,
+			Warn:     30 * 24 * time.Hour,
+			Error:    90 * 24 * time.Hour,
+		},
+	}
+}
+
+// Load reads the configuration for the command line args, without the
+// program name, and returns it with the arguments left after the flags.
+// getenv is usually os.Getenv. The YAML file is read from -config or else
+// CONFIG_FILE when either is set. Every problem found is reported in the
+// returned error, not only the first.
+func Load(args []string, getenv func(string) string) (*Config, []string, error) {
+	cfg := Default()
+	problems := []error{}
+
+	fs := flag.NewFlagSet("wolfscream", flag.ContinueOnError)
+	path := fs.String("config", "", "path of a YAML configuration file")
+	addr := fs.String("addr", "", "address the HTTP server listens on")
+	corsOrigins := fs.String("cors-origins", "", "comma separated origins allowed by CORS")
+	requestTimeout := fs.Duration("request-timeout", 0, "timeout of an API request, 0 disables it")
+	jobTimeout := fs.Duration("job-timeout", 0, "timeout of a scheduled message run, 0 disables it")
+	backplane := fs.String("ws-backplane", "", "WebSocket backplane, postgres or none")
+	migrateOnStart := fs.Bool("migrate-on-start", false, "apply pending migrations before serving")
+	otlpEndpoint := fs.String("otlp-endpoint", "", "URL of the OTLP/HTTP trace collector")
+	traceStdout := fs.Bool("trace-stdout", false, "write the spans to stdout")
+	logLevel := fs.String("log-level", "", "minimum log level, debug, info, warn or error")
+	logFormat := fs.String("log-format", "", "log format, json or text")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *path == "" {
+		*path = getenv("CONFIG_FILE")
+	}
+	if *path != "" {
+		if err := cfg.readFile(*path); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	problems = append(problems, cfg.readEnv(getenv)...)
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Addr = *addr
+		case "cors-origins":
+			cfg.CORSOrigins = splitList(*corsOrigins)
+		case "request-timeout":
+			cfg.RequestTimeout = *requestTimeout
+		case "job-timeout":
+			cfg.Scheduler.JobTimeout = *jobTimeout
+		case "ws-backplane":
+			cfg.WebSocket.Backplane = *backplane
+		case "migrate-on-start":
+			cfg.Database.MigrateOnStart = *migrateOnStart
+		case "otlp-endpoint":
+			cfg.Tracing.OTLPEndpoint = *otlpEndpoint
+		case "trace-stdout":
+			cfg.Tracing.Stdout = *traceStdout
+		case "log-level":
+			cfg.Log.Level = *logLevel
+		case "log-format":
+			cfg.Log.Format = *logFormat
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err)
+	}
+
+	if err := errors.Join(problems...); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func (cfg *Config) readFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// readEnv applies the environment variables that are set and returns the
+// ones that can't be parsed.
+func (cfg *Config) readEnv(getenv func(string) string) []error {
+	problems := []error{}
+
+	values := map[string]*string{
+		"ADDR":              &cfg.Addr,
+		"DB_HOST":           &cfg.Database.Host,
+		"DB_PORT":           &cfg.Database.Port,
+		"DB_USER":           &cfg.Database.User,
+		"DB_PASSWORD":       &cfg.Database.Password,
+		"DB_NAME":           &cfg.Database.Name,
+		"DB_SSLMODE":        &cfg.Database.SSLMode,
+		"DISCORD_BOT_TOKEN": &cfg.Discord.Token,
+		"WS_BACKPLANE":      &cfg.WebSocket.Backplane,
+		"LOG_LEVEL":         &cfg.Log.Level,
+		"LOG_FORMAT":        &cfg.Log.Format,
+		// the variable of the OpenTelemetry SDKs
+		"OTEL_EXPORTER_OTLP_ENDPOINT": &cfg.Tracing.OTLPEndpoint,
+	}
+	for name, field := range values {
+		if value := getenv(name); value != "" {
+			*field = value
+		}
+	}
+
+	if value := getenv("CORS_ORIGINS"); value != "" {
+		cfg.CORSOrigins = splitList(value)
+	}
+
+	durations := map[string]*time.Duration{
+		"REQUEST_TIMEOUT":  &cfg.RequestTimeout,
+		"SHUTDOWN_TIMEOUT": &cfg.ShutdownTimeout,
+		"JOB_TIMEOUT":      &cfg.Scheduler.JobTimeout,
+
+		"LOG_RETENTION_INTERVAL": &cfg.LogRetention.Interval,
+		"LOG_RETENTION_INFO":     &cfg.LogRetention.Info,
+		"LOG_RETENTION_WARN":     &cfg.LogRetention.Warn,
+		"LOG_RETENTION_ERROR":    &cfg.LogRetention.Error,
+	}
+	for name, field := range durations {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: invalid duration %q", name, value))
+			continue
+		}
+		*field = d
+	}
+
+	bools := map[string]*bool{
+		"DB_MIGRATE_ON_START": &cfg.Database.MigrateOnStart,
+		"TRACE_STDOUT":        &cfg.Tracing.Stdout,
+	}
+	for name, field := range bools {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: invalid boolean %q", name, value))
+			continue
+		}
+		*field = b
+	}
+
+	if value := getenv("TRACE_SAMPLE_RATIO"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("TRACE_SAMPLE_RATIO: invalid number %q", value))
+		} else {
+			cfg.Tracing.SampleRatio = ratio
+		}
+	}
+
+	slices.SortFunc(problems, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return problems
+}
+
+// Validate returns every invalid or missing setting, joined in one error.
+func (cfg *Config) Validate() error {
+	problems := []error{}
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+		problem("addr: invalid address %q", cfg.Addr)
+	}
+	if cfg.RequestTimeout < 0 {
+		problem("request_timeout: must not be negative")
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		problem("shutdown_timeout: must be positive")
+	}
+
+	if cfg.Database.Host == "" {
+		problem("database.host: required (DB_HOST)")
+	}
+	if port, err := strconv.Atoi(cfg.Database.Port); err != nil || port < 1 || port > 65535 {
+		problem("database.port: invalid port %q", cfg.Database.Port)
+	}
+	if cfg.Database.User == "" {
+		problem("database.user: required (DB_USER)")
+	}
+	if cfg.Database.Name == "" {
+		problem("database.name: required (DB_NAME)")
+	}
+	if !slices.Contains(sslModes, cfg.Database.SSLMode) {
+		problem("database.sslmode: must be one of %s", strings.Join(sslModes, ", "))
+	}
+
+	if cfg.WebSocket.Backplane != BackplanePostgres && cfg.WebSocket.Backplane != BackplaneNone {
+		problem("websocket.backplane: must be %s or %s", BackplanePostgres, BackplaneNone)
+	}
+
+	if cfg.Scheduler.JobTimeout < 0 {
+		problem("scheduler.job_timeout: must not be negative")
+	}
+
+	if !slices.Contains(logLevels, cfg.Log.Level) {
+		problem("log.level: must be one of %s", strings.Join(logLevels, ", "))
+	}
+	if !slices.Contains(logFormats, cfg.Log.Format) {
+		problem("log.format: must be one of %s", strings.Join(logFormats, ", "))
+	}
+
+	if cfg.LogRetention.Interval <= 0 {
+		problem("log_retention.interval: must be positive")
+	}
+	if cfg.LogRetention.Info < 0 || cfg.LogRetention.Warn < 0 || cfg.LogRetention.Error < 0 {
+		problem("log_retention: ages must not be negative")
+	}
+
+	if endpoint := cfg.Tracing.OTLPEndpoint; endpoint != "" {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problem("tracing.otlp_endpoint: invalid URL %q", endpoint)
+		}
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		problem("tracing.sample_ratio: must be between 0 and 1")
+	}
+
+	return errors.Join(problems...)
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
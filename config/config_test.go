@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+var required = map[string]string{
+	"DB_HOST": "localhost",
+	"DB_USER": "wolfscream",
+	"DB_NAME": "wolfscream",
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, args, err := Load([]string{"migrate", "status"}, env(required))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Addr != ":8080" || cfg.Database.Port != "5432" || !cfg.Database.MigrateOnStart {
+		t.Errorf("defaults not applied: %+v", cfg)
+	}
+	if strings.Join(args, " ") != "migrate status" {
+		t.Errorf("args = %v, want the subcommand", args)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `
+addr: ":9000"
+request_timeout: 10s
+database:
+  host: file-host
+  port: "5433"
+scheduler:
+  job_timeout: 1m
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]string{
+		"DB_USER":      "wolfscream",
+		"DB_NAME":      "wolfscream",
+		"DB_HOST":      "env-host",
+		"JOB_TIMEOUT":  "2m",
+		"CORS_ORIGINS": "https://a.example, https://b.example",
+	}
+
+	cfg, _, err := Load([]string{"-config", path, "-job-timeout", "3m"}, env(values))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Addr != ":9000" || cfg.RequestTimeout != 10*time.Second || cfg.Database.Port != "5433" {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if cfg.Database.Host != "env-host" {
+		t.Errorf("host = %s, want the environment to override the file", cfg.Database.Host)
+	}
+	if cfg.Scheduler.JobTimeout != 3*time.Minute {
+		t.Errorf("job timeout = %s, want the flag to override the environment", cfg.Scheduler.JobTimeout)
+	}
+	if strings.Join(cfg.CORSOrigins, " ") != "https://a.example https://b.example" {
+		t.Errorf("cors origins = %v", cfg.CORSOrigins)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	values := map[string]string{
+		"DB_PORT":            "postgres",
+		"DB_SSLMODE":         "sometimes",
+		"REQUEST_TIMEOUT":    "soon",
+		"WS_BACKPLANE":       "redis",
+		"TRACE_STDOUT":       "loud",
+		"LOG_FORMAT":         "xml",
+		"LOG_RETENTION_WARN": "-1h",
+
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "collector:4318",
+	}
+
+	_, _, err := Load(nil, env(values))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{
+		"REQUEST_TIMEOUT",
+		"database.host",
+		"database.port",
+		"database.user",
+		"database.name",
+		"database.sslmode",
+		"websocket.backplane",
+		"TRACE_STDOUT",
+		"tracing.otlp_endpoint",
+		"log.format",
+		"log_retention",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("adress: \":9000\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Load([]string{"-config", path}, env(required)); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
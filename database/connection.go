@@ -1,39 +1,42 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"log"
-	"os"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var (
-	DB *sql.DB
-)
-
-func init() {
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-	sslmode := os.Getenv("DB_SSLMODE")
-
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
-		host, port, user, dbname, password, sslmode,
+// Open connects to Postgres with dsn and checks the connection. The DSN is
+// also needed by the LISTEN/NOTIFY listeners, which can't use the pool.
+//
+// Every query made within a trace gets a span. Queries outside of one, such
+// as the pings of the pool, aren't traced so they don't each start a trace.
+func Open(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := otelsql.Open("postgres", dsn,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnectorConnect: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
 	)
-
-	db, err := sql.Open("postgres", dsn)
 	if err != nil {
-		log.Fatalf("Failed to open DB: %v", err)
+		return nil, fmt.Errorf("failed to open DB: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping DB: %v", err)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping DB: %w", err)
 	}
 
-	DB = db
+	return db, nil
 }
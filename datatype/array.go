@@ -0,0 +1,112 @@
+package datatype
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatArray renders elements as a Postgres array literal. nil elements are
+// NULL, every other element is quoted.
+func FormatArray(elements []*string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, element := range elements {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if element == nil {
+			b.WriteString("NULL")
+			continue
+		}
+		b.WriteByte('"')
+		for _, r := range *element {
+			if r == '"' || r == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// ParseArray parses a one dimensional Postgres array literal as returned for
+// text[] columns. Unquoted NULL elements are nil.
+func ParseArray(literal string) ([]*string, error) {
+	literal = strings.TrimSpace(literal)
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return nil, fmt.Errorf("%q is not an array literal", literal)
+	}
+
+	body := literal[1 : len(literal)-1]
+	elements := []*string{}
+	if strings.TrimSpace(body) == "" {
+		return elements, nil
+	}
+
+	for i := 0; ; {
+		for i < len(body) && body[i] == ' ' {
+			i++
+		}
+
+		var (
+			element strings.Builder
+			quoted  bool
+		)
+		if i < len(body) && body[i] == '"' {
+			quoted = true
+			i++
+			for {
+				if i >= len(body) {
+					return nil, fmt.Errorf("unterminated quoted element in %q", literal)
+				}
+				c := body[i]
+				if c == '"' {
+					i++
+					break
+				}
+				if c == '\\' && i+1 < len(body) {
+					i++
+					c = body[i]
+				}
+				element.WriteByte(c)
+				i++
+			}
+			for i < len(body) && body[i] == ' ' {
+				i++
+			}
+		} else {
+			for i < len(body) && body[i] != ',' {
+				c := body[i]
+				if c == '{' || c == '}' || c == '"' {
+					return nil, fmt.Errorf("only one dimensional arrays are supported")
+				}
+				if c == '\\' && i+1 < len(body) {
+					i++
+					c = body[i]
+				}
+				element.WriteByte(c)
+				i++
+			}
+		}
+
+		text := element.String()
+		if !quoted {
+			text = strings.TrimSpace(text)
+		}
+		if !quoted && strings.EqualFold(text, "NULL") {
+			elements = append(elements, nil)
+		} else {
+			elements = append(elements, &text)
+		}
+
+		if i >= len(body) {
+			return elements, nil
+		}
+		if body[i] != ',' {
+			return nil, fmt.Errorf("unexpected %q in %q", body[i], literal)
+		}
+		i++
+	}
+}
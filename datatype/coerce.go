@@ -0,0 +1,253 @@
+package datatype
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	uuidPattern    = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+	numericPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE]([+-]?\d{1,4}))?$`)
+)
+
+// Coerce converts a decoded JSON value into the value stored in a column of
+// type spec. nil stays nil. Decoders should use json.Decoder.UseNumber so
+// int8 and numeric values keep all their digits.
+func Coerce(spec Spec, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	// any JSON document is a jsonb value, numbers included
+	if spec.Type == "jsonb" {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be JSON: %v", err)
+		}
+		return string(encoded), nil
+	}
+
+	if n, ok := value.(json.Number); ok {
+		value = string(n)
+	}
+
+	switch spec.Type {
+	case "int4":
+		return coerceInt(value, math.MinInt32, math.MaxInt32)
+	case "int8":
+		return coerceInt(value, math.MinInt64, math.MaxInt64)
+	case "float4", "float8":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", v)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("must be a number")
+	case "numeric":
+		return coerceNumeric(spec, value)
+	case "varchar", "text":
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		if spec.Type == "varchar" && spec.Length != nil && utf8.RuneCountInString(v) > *spec.Length {
+			return nil, fmt.Errorf("must be at most %d characters", *spec.Length)
+		}
+		return v, nil
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a boolean", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("must be a boolean")
+	case "timestamptz":
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be an RFC 3339 timestamp")
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an RFC 3339 timestamp", v)
+		}
+		return t, nil
+	case "date":
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a date formatted as YYYY-MM-DD")
+		}
+		if _, err := time.Parse(dateLayout, v); err != nil {
+			return nil, fmt.Errorf("%q is not a date formatted as YYYY-MM-DD", v)
+		}
+		return v, nil
+	case "uuid":
+		v, ok := value.(string)
+		if !ok || !uuidPattern.MatchString(v) {
+			return nil, fmt.Errorf("must be a UUID")
+		}
+		return strings.ToLower(v), nil
+	case "inet":
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be an IP address")
+		}
+		if addr, err := netip.ParseAddr(v); err == nil {
+			return addr.String(), nil
+		}
+		if prefix, err := netip.ParsePrefix(v); err == nil {
+			return prefix.String(), nil
+		}
+		return nil, fmt.Errorf("%q is not an IP address", v)
+	case "enum":
+		v, ok := value.(string)
+		if !ok || !slices.Contains(spec.EnumValues, v) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(spec.EnumValues, ", "))
+		}
+		return v, nil
+	case "text[]":
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+		elements := make([]*string, len(items))
+		for i, item := range items {
+			switch v := item.(type) {
+			case nil:
+			case string:
+				elements[i] = &v
+			default:
+				return nil, fmt.Errorf("must be an array of strings")
+			}
+		}
+		return FormatArray(elements), nil
+	}
+
+	return nil, fmt.Errorf("unknown column type %s", spec.Type)
+}
+
+// CoerceText converts the text of a CSV field into the value stored in a
+// column of type spec. jsonb fields hold a JSON document and text[] fields a
+// JSON array or a Postgres array literal.
+func CoerceText(spec Spec, value string) (any, error) {
+	switch spec.Type {
+	case "jsonb":
+		if !json.Valid([]byte(value)) {
+			return nil, fmt.Errorf("must be JSON")
+		}
+		return value, nil
+	case "text[]":
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			var items []any
+			if err := json.Unmarshal([]byte(value), &items); err != nil {
+				return nil, fmt.Errorf("must be a JSON array")
+			}
+			return Coerce(spec, items)
+		}
+		elements, err := ParseArray(value)
+		if err != nil {
+			return nil, err
+		}
+		return FormatArray(elements), nil
+	}
+	return Coerce(spec, value)
+}
+
+func coerceInt(value any, min int64, max int64) (any, error) {
+	var n int64
+
+	switch v := value.(type) {
+	case float64:
+		// -float64(min) is max+1 and, unlike float64(max), exact
+		if v != math.Trunc(v) || v < float64(min) || v >= -float64(min) {
+			return nil, fmt.Errorf("%v is not a valid integer", v)
+		}
+		n = int64(v)
+	case string:
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid integer", v)
+		}
+		n = parsed
+	default:
+		return nil, fmt.Errorf("must be an integer")
+	}
+
+	if n < min || n > max {
+		return nil, fmt.Errorf("%d is out of range", n)
+	}
+	return n, nil
+}
+
+// coerceNumeric returns the value as decimal text, rounded to the scale of
+// the column like Postgres does, and checks that it fits the precision.
+func coerceNumeric(spec Spec, value any) (any, error) {
+	var text string
+	switch v := value.(type) {
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		text = strings.TrimSpace(v)
+	default:
+		return nil, fmt.Errorf("must be a number")
+	}
+
+	// the exponent is limited by the pattern, so SetString can't be asked to
+	// build a huge number
+	if !numericPattern.MatchString(text) {
+		return nil, fmt.Errorf("%q is not a number", text)
+	}
+	if spec.Precision == nil {
+		return text, nil
+	}
+
+	n, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a number", text)
+	}
+
+	scale := 0
+	if spec.Scale != nil {
+		scale = *spec.Scale
+	}
+	rounded := n.FloatString(scale)
+
+	digits := strings.TrimLeft(strings.SplitN(strings.TrimPrefix(rounded, "-"), ".", 2)[0], "0")
+	if len(digits) > *spec.Precision-scale {
+		return nil, fmt.Errorf("%s doesn't fit numeric(%d, %d)", text, *spec.Precision, scale)
+	}
+	return rounded, nil
+}
+
+// Text renders a coerced value the way Postgres reads it from text.
+func Text(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
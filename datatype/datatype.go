@@ -0,0 +1,229 @@
+// Package datatype knows the column types that can be used in user defined
+// tables and converts request values into values for those columns.
+package datatype
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Spec describes the type of a column as stored in user_defined_column.
+// Length applies to varchar, Precision and Scale to numeric. Enum columns use
+// a Postgres enum type, EnumType, created for the column with EnumValues.
+type Spec struct {
+	Type       string   `json:"type"`
+	Length     *int     `json:"length"`
+	Precision  *int     `json:"precision,omitempty"`
+	Scale      *int     `json:"scale,omitempty"`
+	EnumType   string   `json:"enum_type,omitempty"`
+	EnumValues []string `json:"enum_values,omitempty"`
+}
+
+// Types lists the column types accepted by AddColumn.
+var Types = []string{
+	"int4", "int8", "float4", "float8", "numeric",
+	"varchar", "text", "bool",
+	"timestamptz", "date",
+	"jsonb", "uuid", "inet",
+	"enum", "text[]",
+}
+
+const (
+	maxNumericPrecision = 1000
+	maxEnumLabelLength  = 63
+	maxIdentifierLength = 63
+)
+
+// EnumTypeName returns the name of the enum type backing an enum column.
+func EnumTypeName(table string, column string) string {
+	return Identifier("enum", table, column)
+}
+
+// ConversionEnumTypeName returns the name of the enum type a column is
+// converted to before it takes the name of EnumTypeName. No column uses it.
+func ConversionEnumTypeName(table string, column string) string {
+	return Identifier("new", table, column)
+}
+
+// Identifier returns the name of an object created for the given table and
+// columns, such as an enum type or a constraint. The names are only kept for
+// readability, a hash of them makes the identifier unique: "a_b" and "c"
+// don't share a name with "a" and "b_c", nor do names that are cut to fit
+// the identifier length.
+func Identifier(suffix string, names ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(names, "\x00")))
+	hash := hex.EncodeToString(sum[:4])
+
+	name := strings.Join(names, "_")
+	if max := maxIdentifierLength - len(hash) - len(suffix) - 2; len(name) > max {
+		name = name[:max]
+	}
+	return name + "_" + hash + "_" + suffix
+}
+
+// Validate checks that spec names a known type with the parameters it needs.
+func Validate(spec Spec) error {
+	known := false
+	for _, t := range Types {
+		known = known || t == spec.Type
+	}
+	if !known {
+		return fmt.Errorf("unknown column type: %s", spec.Type)
+	}
+
+	if spec.Length != nil && spec.Type != "varchar" {
+		return fmt.Errorf("length only applies to varchar")
+	}
+	if (spec.Precision != nil || spec.Scale != nil) && spec.Type != "numeric" {
+		return fmt.Errorf("precision and scale only apply to numeric")
+	}
+	if len(spec.EnumValues) > 0 && spec.Type != "enum" {
+		return fmt.Errorf("values only apply to enum")
+	}
+
+	switch spec.Type {
+	case "varchar":
+		if spec.Length == nil || *spec.Length < 1 {
+			return fmt.Errorf("length is required for type varchar")
+		}
+	case "numeric":
+		if spec.Scale != nil && spec.Precision == nil {
+			return fmt.Errorf("scale requires precision")
+		}
+		if spec.Precision != nil && (*spec.Precision < 1 || *spec.Precision > maxNumericPrecision) {
+			return fmt.Errorf("precision must be between 1 and %d", maxNumericPrecision)
+		}
+		if spec.Scale != nil && (*spec.Scale < 0 || *spec.Scale > *spec.Precision) {
+			return fmt.Errorf("scale must be between 0 and the precision")
+		}
+	case "enum":
+		if len(spec.EnumValues) == 0 {
+			return fmt.Errorf("values are required for type enum")
+		}
+		seen := map[string]bool{}
+		for _, value := range spec.EnumValues {
+			if value == "" || len(value) > maxEnumLabelLength {
+				return fmt.Errorf("enum values must be 1 to %d bytes long", maxEnumLabelLength)
+			}
+			if seen[value] {
+				return fmt.Errorf("duplicate enum value %q", value)
+			}
+			seen[value] = true
+		}
+	}
+
+	return nil
+}
+
+// Equal reports whether two specs describe the same column type.
+func Equal(a Spec, b Spec) bool {
+	return a.Type == b.Type &&
+		equalInt(a.Length, b.Length) &&
+		equalInt(a.Precision, b.Precision) &&
+		equalInt(a.Scale, b.Scale) &&
+		a.EnumType == b.EnumType &&
+		slices.Equal(a.EnumValues, b.EnumValues)
+}
+
+func equalInt(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// SQL returns the type of spec as used in CREATE and ALTER TABLE.
+func SQL(spec Spec) string {
+	switch spec.Type {
+	case "int4":
+		return "INTEGER"
+	case "int8":
+		return "BIGINT"
+	case "float4":
+		return "REAL"
+	case "float8":
+		return "DOUBLE PRECISION"
+	case "numeric":
+		if spec.Precision != nil && spec.Scale != nil {
+			return fmt.Sprintf("NUMERIC(%d, %d)", *spec.Precision, *spec.Scale)
+		}
+		if spec.Precision != nil {
+			return fmt.Sprintf("NUMERIC(%d)", *spec.Precision)
+		}
+		return "NUMERIC"
+	case "varchar":
+		if spec.Length != nil {
+			return fmt.Sprintf("VARCHAR(%d)", *spec.Length)
+		}
+		return "VARCHAR"
+	case "text":
+		return "TEXT"
+	case "bool":
+		return "BOOLEAN"
+	case "timestamptz":
+		return "TIMESTAMPTZ"
+	case "date":
+		return "DATE"
+	case "jsonb":
+		return "JSONB"
+	case "uuid":
+		return "UUID"
+	case "inet":
+		return "INET"
+	case "enum":
+		return pq.QuoteIdentifier(spec.EnumType)
+	case "text[]":
+		return "TEXT[]"
+	}
+	return spec.Type
+}
+
+// Cast returns the type a text parameter is cast to when it is compared with
+// a column of type spec.
+func Cast(spec Spec) string {
+	switch spec.Type {
+	case "enum":
+		return pq.QuoteIdentifier(spec.EnumType)
+	}
+	return spec.Type
+}
+
+// CreateEnum returns the statement creating the enum type of spec.
+func CreateEnum(spec Spec) string {
+	labels := make([]string, len(spec.EnumValues))
+	for i, value := range spec.EnumValues {
+		labels[i] = pq.QuoteLiteral(value)
+	}
+	return fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", pq.QuoteIdentifier(spec.EnumType), strings.Join(labels, ", "))
+}
+
+// Literal renders value as an SQL literal of type spec, for DEFAULT clauses.
+func Literal(spec Spec, value any) (string, error) {
+	coerced, err := Coerce(spec, value)
+	if err != nil {
+		return "", err
+	}
+	return literal(spec, coerced), nil
+}
+
+// TextLiteral is Literal for values written as text, like the values of check
+// expressions.
+func TextLiteral(spec Spec, value string) (string, error) {
+	coerced, err := CoerceText(spec, value)
+	if err != nil {
+		return "", err
+	}
+	return literal(spec, coerced), nil
+}
+
+func literal(spec Spec, coerced any) string {
+	if coerced == nil {
+		return "NULL"
+	}
+	return pq.QuoteLiteral(Text(coerced)) + "::" + Cast(spec)
+}
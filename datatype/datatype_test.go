@@ -0,0 +1,206 @@
+package datatype
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		spec Spec
+		ok   bool
+	}{
+		{Spec{Type: "text"}, true},
+		{Spec{Type: "varchar"}, false},
+		{Spec{Type: "varchar", Length: intPtr(10)}, true},
+		{Spec{Type: "text", Length: intPtr(10)}, false},
+		{Spec{Type: "numeric"}, true},
+		{Spec{Type: "numeric", Precision: intPtr(10), Scale: intPtr(2)}, true},
+		{Spec{Type: "numeric", Scale: intPtr(2)}, false},
+		{Spec{Type: "numeric", Precision: intPtr(2), Scale: intPtr(3)}, false},
+		{Spec{Type: "enum"}, false},
+		{Spec{Type: "enum", EnumValues: []string{"low", "low"}}, false},
+		{Spec{Type: "enum", EnumValues: []string{"low", "high"}}, true},
+		{Spec{Type: "money"}, false},
+	}
+
+	for _, test := range tests {
+		if err := Validate(test.spec); (err == nil) != test.ok {
+			t.Errorf("Validate(%+v) = %v, want ok %v", test.spec, err, test.ok)
+		}
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	numeric := Spec{Type: "numeric", Precision: intPtr(5), Scale: intPtr(2)}
+	severity := Spec{Type: "enum", EnumValues: []string{"low", "high"}}
+
+	tests := []struct {
+		spec  Spec
+		value any
+		want  any
+	}{
+		{Spec{Type: "int8"}, json.Number("9007199254740993"), int64(9007199254740993)},
+		{Spec{Type: "timestamptz"}, "2025-01-02T03:04:05+07:00", time.Date(2025, 1, 1, 20, 4, 5, 0, time.UTC)},
+		{Spec{Type: "date"}, "2025-01-02", "2025-01-02"},
+		{Spec{Type: "jsonb"}, map[string]any{"a": json.Number("1")}, `{"a":1}`},
+		{Spec{Type: "jsonb"}, "text", `"text"`},
+		{Spec{Type: "uuid"}, "A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11", "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		{numeric, json.Number("123.456"), "123.46"},
+		{numeric, "-0.005", "-0.01"},
+		{Spec{Type: "numeric"}, "1e3", "1e3"},
+		{Spec{Type: "inet"}, "10.0.0.1", "10.0.0.1"},
+		{Spec{Type: "inet"}, "10.0.0.0/8", "10.0.0.0/8"},
+		{severity, "high", "high"},
+		{Spec{Type: "text[]"}, []any{"a", nil, `b"\`}, `{"a",NULL,"b\"\\"}`},
+	}
+
+	for _, test := range tests {
+		got, err := Coerce(test.spec, test.value)
+		if err != nil {
+			t.Errorf("Coerce(%s, %v) failed: %v", test.spec.Type, test.value, err)
+			continue
+		}
+		if want, ok := test.want.(time.Time); ok {
+			if !want.Equal(got.(time.Time)) {
+				t.Errorf("Coerce(%s, %v) = %v, want %v", test.spec.Type, test.value, got, want)
+			}
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Coerce(%s, %v) = %v, want %v", test.spec.Type, test.value, got, test.want)
+		}
+	}
+
+	invalid := []struct {
+		spec  Spec
+		value any
+	}{
+		{numeric, "1000"},
+		{numeric, "1e99999"},
+		{Spec{Type: "timestamptz"}, "2025-01-02"},
+		{Spec{Type: "date"}, "02/01/2025"},
+		{Spec{Type: "uuid"}, "not-a-uuid"},
+		{Spec{Type: "inet"}, "300.0.0.1"},
+		{severity, "medium"},
+		{Spec{Type: "text[]"}, []any{json.Number("1")}},
+	}
+
+	for _, test := range invalid {
+		if got, err := Coerce(test.spec, test.value); err == nil {
+			t.Errorf("Coerce(%s, %v) = %v, want an error", test.spec.Type, test.value, got)
+		}
+	}
+}
+
+func TestCoerceText(t *testing.T) {
+	got, err := CoerceText(Spec{Type: "jsonb"}, `{"a": 1}`)
+	if err != nil || got != `{"a": 1}` {
+		t.Errorf("jsonb: got %v, %v", got, err)
+	}
+
+	got, err = CoerceText(Spec{Type: "text[]"}, `["a","b"]`)
+	if err != nil || got != `{"a","b"}` {
+		t.Errorf("text[] from JSON: got %v, %v", got, err)
+	}
+
+	got, err = CoerceText(Spec{Type: "text[]"}, `{a, "b,c", NULL}`)
+	if err != nil || got != `{"a","b,c",NULL}` {
+		t.Errorf("text[] from literal: got %v, %v", got, err)
+	}
+}
+
+func TestArrayRoundTrip(t *testing.T) {
+	a, b := `x "y" \z`, "NULL"
+	elements := []*string{&a, nil, &b}
+
+	parsed, err := ParseArray(FormatArray(elements))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 3 || *parsed[0] != a || parsed[1] != nil || *parsed[2] != b {
+		t.Fatalf("round trip changed the elements: %v", parsed)
+	}
+}
+
+func TestOutput(t *testing.T) {
+	if got := Output(Spec{Type: "jsonb"}, []byte(`{"a":1}`)); !reflect.DeepEqual(got, json.RawMessage(`{"a":1}`)) {
+		t.Errorf("jsonb: got %#v", got)
+	}
+	if got := Output(Spec{Type: "text[]"}, []byte(`{a,NULL}`)); !reflect.DeepEqual(got, []any{"a", nil}) {
+		t.Errorf("text[]: got %#v", got)
+	}
+	if got := Output(Spec{Type: "numeric"}, []byte("1.50")); got != "1.50" {
+		t.Errorf("numeric: got %#v", got)
+	}
+	if got := Output(Spec{Type: "date"}, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)); got != "2025-01-02" {
+		t.Errorf("date: got %#v", got)
+	}
+}
+
+func TestEnumTypeName(t *testing.T) {
+	long := strings.Repeat("x", 63)
+
+	names := map[string]string{}
+	for _, pair := range [][2]string{
+		{"a_b", "c"},
+		{"a", "b_c"},
+		{long, "a"},
+		{long, "b"},
+		{"alerts", "severity"},
+	} {
+		name := EnumTypeName(pair[0], pair[1])
+		if len(name) > maxIdentifierLength {
+			t.Errorf("EnumTypeName(%q, %q) = %q is longer than %d", pair[0], pair[1], name, maxIdentifierLength)
+		}
+		if other, ok := names[name]; ok {
+			t.Errorf("EnumTypeName(%q, %q) = %q, the name of %s", pair[0], pair[1], name, other)
+		}
+		names[name] = pair[0] + "." + pair[1]
+	}
+
+	if name := EnumTypeName("alerts", "severity"); !strings.HasPrefix(name, "alerts_severity_") || !strings.HasSuffix(name, "_enum") {
+		t.Errorf("EnumTypeName(alerts, severity) = %q", name)
+	}
+	if EnumTypeName("alerts", "severity") != EnumTypeName("alerts", "severity") {
+		t.Error("EnumTypeName isn't stable")
+	}
+	if ConversionEnumTypeName("alerts", "severity") == EnumTypeName("alerts", "severity") {
+		t.Error("the conversion type has the name of the column type")
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	if Identifier("idx", "t", "a_b", "c") == Identifier("idx", "t", "a", "b_c") {
+		t.Error("indexes of different columns share a name")
+	}
+	if Identifier("key", "a_b", "c") == Identifier("key", "a", "b_c") {
+		t.Error("constraints of different columns share a name")
+	}
+	long := strings.Repeat("x", 63)
+	if name := Identifier("fkey", long, "a"); len(name) > maxIdentifierLength || name == Identifier("fkey", long, "b") {
+		t.Errorf("Identifier of a long table = %q", name)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	enum := Spec{Type: "enum", EnumType: "alerts_severity_enum", EnumValues: []string{"low", "high"}}
+	reordered := Spec{Type: "enum", EnumType: "alerts_severity_enum", EnumValues: []string{"high", "low"}}
+
+	if !Equal(enum, enum) {
+		t.Error("enum differs from itself")
+	}
+	if Equal(enum, reordered) {
+		t.Error("enum values are ordered")
+	}
+	if Equal(Spec{Type: "numeric", Precision: intPtr(10)}, Spec{Type: "numeric"}) {
+		t.Error("precision is ignored")
+	}
+}
@@ -0,0 +1,41 @@
+package datatype
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Output converts a value scanned from a column of type spec into the value
+// returned in JSON responses. Types the driver returns as text, like numeric
+// and uuid, become strings so they don't get base64 encoded, jsonb is kept as
+// raw JSON and text[] becomes an array.
+func Output(spec Spec, value any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		switch spec.Type {
+		case "jsonb":
+			return json.RawMessage(v)
+		case "text[]":
+			elements, err := ParseArray(string(v))
+			if err != nil {
+				return string(v)
+			}
+			items := make([]any, len(elements))
+			for i, element := range elements {
+				if element != nil {
+					items[i] = *element
+				}
+			}
+			return items
+		}
+		return string(v)
+	case time.Time:
+		if spec.Type == "date" {
+			return v.Format(dateLayout)
+		}
+		return v
+	}
+	return value
+}
@@ -1,30 +1,21 @@
 package discord
 
 import (
-	"log"
-	"os"
+	"fmt"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-var DiscordBot *discordgo.Session
-
-func init() {
-	token := os.Getenv("DISCORD_BOT_TOKEN")
-	if token == "" {
-		log.Fatalf("DISCORD_BOT_TOKEN environment variable is not set")
-	}
-
-	var err error
+// Open creates a bot session for token and connects it to the gateway.
+func Open(token string) (*discordgo.Session, error) {
 	bot, err := discordgo.New("Bot " + token)
 	if err != nil {
-		log.Fatalf("Failed to create Discord session: %v", err)
+		return nil, fmt.Errorf("failed to create Discord session: %w", err)
 	}
 
-	err = bot.Open()
-	if err != nil {
-		log.Fatalf("Failed to open connection: %v", err)
+	if err := bot.Open(); err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
 	}
 
-	DiscordBot = bot
+	return bot, nil
 }
@@ -0,0 +1,139 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"wolfscream/notifier"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("wolfscream/discord")
+
+// retryInterval is the minimum time between two connection attempts, so a
+// bad token or an outage doesn't hammer the gateway on every job run.
+const retryInterval = 30 * time.Second
+
+// Client connects the bot on first use. Without a token it stays
+// unconfigured and every use returns notifier.ErrNotConfigured.
+type Client struct {
+	token   string
+	open    func(token string) (*discordgo.Session, error)
+	observe notifier.SendObserver
+
+	mu          sync.Mutex
+	session     *discordgo.Session
+	err         error
+	lastAttempt time.Time
+}
+
+func New(token string) *Client {
+	return &Client{token: token, open: Open}
+}
+
+// Observe sets the observer of Send. It must be called before the client is
+// used.
+func (c *Client) Observe(observe notifier.SendObserver) {
+	c.observe = observe
+}
+
+// Send posts content to a channel, connecting the bot when needed.
+func (c *Client) Send(ctx context.Context, channelId string, content string) error {
+	ctx, span := tracer.Start(ctx, "notifier.send", trace.WithAttributes(
+		attribute.String("notifier.platform", "discord"),
+		attribute.String("discord.channel_id", channelId),
+	))
+	defer span.End()
+
+	start := time.Now()
+
+	session, err := c.Session()
+	if err == nil {
+		_, err = session.ChannelMessageSend(channelId, content, discordgo.WithContext(ctx))
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if c.observe != nil {
+		c.observe("discord", time.Since(start), err)
+	}
+	return err
+}
+
+// Session returns the connected session, connecting it when needed. After a
+// failed attempt the error is returned until retryInterval has passed.
+func (c *Client) Session() (*discordgo.Session, error) {
+	if c.token == "" {
+		return nil, notifier.ErrNotConfigured
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session != nil {
+		return c.session, nil
+	}
+	if c.err != nil && time.Since(c.lastAttempt) < retryInterval {
+		return nil, c.err
+	}
+
+	c.lastAttempt = time.Now()
+	session, err := c.open(c.token)
+	if err != nil {
+		c.err = fmt.Errorf("%w: %v", notifier.ErrUnavailable, err)
+		return nil, c.err
+	}
+
+	c.session, c.err = session, nil
+	return session, nil
+}
+
+// Status reports the state of the gateway connection without connecting.
+// discordgo marks the session ready while the gateway answers heartbeats and
+// clears it when the connection drops, until it has reconnected.
+func (c *Client) Status() notifier.Status {
+	if c.token == "" {
+		return notifier.Status{State: notifier.StateNotConfigured}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case c.session != nil:
+		c.session.RLock()
+		ready := c.session.DataReady
+		c.session.RUnlock()
+
+		if !ready {
+			return notifier.Status{State: notifier.StateDisconnected, Error: "gateway connection lost"}
+		}
+		return notifier.Status{State: notifier.StateConnected}
+	case c.err != nil:
+		return notifier.Status{State: notifier.StateUnavailable, Error: c.err.Error()}
+	default:
+		return notifier.Status{State: notifier.StateIdle}
+	}
+}
+
+// Close disconnects the session, if it was ever opened.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == nil {
+		return nil
+	}
+	err := c.session.Close()
+	c.session = nil
+	return err
+}
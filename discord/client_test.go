@@ -0,0 +1,79 @@
+package discord
+
+import (
+	"errors"
+	"testing"
+
+	"wolfscream/notifier"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestClientNotConfigured(t *testing.T) {
+	c := New("")
+
+	if _, err := c.Session(); !errors.Is(err, notifier.ErrNotConfigured) {
+		t.Errorf("Session() error = %v, want ErrNotConfigured", err)
+	}
+	if state := c.Status().State; state != notifier.StateNotConfigured {
+		t.Errorf("state = %s, want %s", state, notifier.StateNotConfigured)
+	}
+}
+
+func TestClientRetriesAfterInterval(t *testing.T) {
+	attempts := 0
+	c := New("token")
+	c.open = func(token string) (*discordgo.Session, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("gateway down")
+		}
+		return &discordgo.Session{DataReady: true}, nil
+	}
+
+	if state := c.Status().State; state != notifier.StateIdle {
+		t.Errorf("state before first use = %s, want %s", state, notifier.StateIdle)
+	}
+
+	if _, err := c.Session(); !errors.Is(err, notifier.ErrUnavailable) {
+		t.Fatalf("Session() error = %v, want ErrUnavailable", err)
+	}
+	if state := c.Status().State; state != notifier.StateUnavailable {
+		t.Errorf("state after failure = %s, want %s", state, notifier.StateUnavailable)
+	}
+
+	// within the retry interval the failure is returned without dialing
+	if _, err := c.Session(); err == nil || attempts != 1 {
+		t.Errorf("Session() = %v after %d attempts, want the cached failure", err, attempts)
+	}
+
+	c.lastAttempt = c.lastAttempt.Add(-retryInterval)
+	if _, err := c.Session(); err != nil {
+		t.Fatalf("Session() error = %v after the retry interval", err)
+	}
+	if state := c.Status().State; state != notifier.StateConnected {
+		t.Errorf("state = %s, want %s", state, notifier.StateConnected)
+	}
+}
+
+func TestClientStatusFollowsGateway(t *testing.T) {
+	session := &discordgo.Session{DataReady: true}
+	c := New("token")
+	c.open = func(token string) (*discordgo.Session, error) {
+		return session, nil
+	}
+	if _, err := c.Session(); err != nil {
+		t.Fatal(err)
+	}
+
+	// discordgo clears DataReady when the gateway drops
+	session.DataReady = false
+	if state := c.Status().State; state != notifier.StateDisconnected {
+		t.Errorf("state after the gateway dropped = %s, want %s", state, notifier.StateDisconnected)
+	}
+
+	session.DataReady = true
+	if state := c.Status().State; state != notifier.StateConnected {
+		t.Errorf("state after reconnecting = %s, want %s", state, notifier.StateConnected)
+	}
+}
@@ -0,0 +1,185 @@
+// Package drift compares the user defined tables registered in the metadata
+// tables with the tables that exist in the Postgres catalog.
+package drift
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"wolfscream/datatype"
+)
+
+// InternalTables are the tables of the application itself. They are never
+// reported as unregistered user tables.
+var InternalTables = []string{
+	"user_defined_table",
+	"user_defined_column",
+	"user_defined_index",
+	"communication_platform",
+	"scheduled_message",
+	"scheduled_message_interval",
+	"scheduled_message_cron",
+	"scheduled_message_rule",
+	"running_scheduled_message",
+	"scheduled_message_execution_history",
+	"scheduled_message_state_history",
+	"scheduled_message_log",
+	"scheduled_message_discord_config",
+	"message_template",
+	"schema_migrations",
+}
+
+// IdColumn is the primary key added to every user table. It isn't registered
+// as a column, so it is never reported.
+const IdColumn = "id"
+
+const (
+	UnregisteredTable  = "unregistered_table"
+	MissingTable       = "missing_table"
+	UnregisteredColumn = "unregistered_column"
+	MissingColumn      = "missing_column"
+	TypeMismatch       = "type_mismatch"
+	NullableMismatch   = "nullable_mismatch"
+)
+
+type Column struct {
+	Name     string
+	Spec     datatype.Spec
+	Nullable bool
+}
+
+type Table struct {
+	Name    string
+	Columns []Column
+}
+
+// Issue is a difference between the metadata and the catalog. Metadata and
+// Catalog hold the column type on each side when it applies.
+type Issue struct {
+	Kind     string         `json:"kind"`
+	Table    string         `json:"table"`
+	Column   string         `json:"column,omitempty"`
+	Message  string         `json:"message"`
+	Metadata *datatype.Spec `json:"metadata,omitempty"`
+	Catalog  *datatype.Spec `json:"catalog,omitempty"`
+}
+
+// IsInternal reports whether a table belongs to the application.
+func IsInternal(table string) bool {
+	return slices.Contains(InternalTables, table)
+}
+
+// Compare returns the differences between the registered tables and the
+// tables of the catalog, sorted by table and column.
+func Compare(metadata []Table, catalog []Table) []Issue {
+	issues := []Issue{}
+
+	registered := map[string]Table{}
+	for _, table := range metadata {
+		registered[table.Name] = table
+	}
+	existing := map[string]Table{}
+	for _, table := range catalog {
+		if !IsInternal(table.Name) {
+			existing[table.Name] = table
+		}
+	}
+
+	for name, table := range existing {
+		if _, ok := registered[name]; !ok {
+			issues = append(issues, Issue{
+				Kind:    UnregisteredTable,
+				Table:   name,
+				Message: fmt.Sprintf("table %s exists but isn't registered", name),
+			})
+			continue
+		}
+		issues = append(issues, compareColumns(registered[name], table)...)
+	}
+
+	for name := range registered {
+		if _, ok := existing[name]; !ok {
+			issues = append(issues, Issue{
+				Kind:    MissingTable,
+				Table:   name,
+				Message: fmt.Sprintf("table %s is registered but doesn't exist", name),
+			})
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Table != issues[j].Table {
+			return issues[i].Table < issues[j].Table
+		}
+		if issues[i].Column != issues[j].Column {
+			return issues[i].Column < issues[j].Column
+		}
+		return issues[i].Kind < issues[j].Kind
+	})
+
+	return issues
+}
+
+func compareColumns(metadata Table, catalog Table) []Issue {
+	issues := []Issue{}
+
+	registered := map[string]Column{}
+	for _, column := range metadata.Columns {
+		registered[column.Name] = column
+	}
+	existing := map[string]Column{}
+	for _, column := range catalog.Columns {
+		if column.Name != IdColumn {
+			existing[column.Name] = column
+		}
+	}
+
+	for name, actual := range existing {
+		column, ok := registered[name]
+		if !ok {
+			issues = append(issues, Issue{
+				Kind:    UnregisteredColumn,
+				Table:   metadata.Name,
+				Column:  name,
+				Message: fmt.Sprintf("column %s.%s exists but isn't registered", metadata.Name, name),
+				Catalog: &actual.Spec,
+			})
+			continue
+		}
+
+		if !datatype.Equal(column.Spec, actual.Spec) {
+			issues = append(issues, Issue{
+				Kind:     TypeMismatch,
+				Table:    metadata.Name,
+				Column:   name,
+				Message:  fmt.Sprintf("column %s.%s is registered as %s but is %s", metadata.Name, name, datatype.SQL(column.Spec), datatype.SQL(actual.Spec)),
+				Metadata: &column.Spec,
+				Catalog:  &actual.Spec,
+			})
+		}
+
+		if column.Nullable != actual.Nullable {
+			issues = append(issues, Issue{
+				Kind:    NullableMismatch,
+				Table:   metadata.Name,
+				Column:  name,
+				Message: fmt.Sprintf("column %s.%s is registered with nullable %t but is nullable %t", metadata.Name, name, column.Nullable, actual.Nullable),
+			})
+		}
+	}
+
+	for name, column := range registered {
+		if _, ok := existing[name]; !ok {
+			issues = append(issues, Issue{
+				Kind:     MissingColumn,
+				Table:    metadata.Name,
+				Column:   name,
+				Message:  fmt.Sprintf("column %s.%s is registered but doesn't exist", metadata.Name, name),
+				Metadata: &column.Spec,
+			})
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,52 @@
+package drift
+
+import (
+	"testing"
+
+	"wolfscream/datatype"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestCompare(t *testing.T) {
+	metadata := []Table{
+		{Name: "alerts", Columns: []Column{
+			{Name: "severity", Spec: datatype.Spec{Type: "varchar", Length: intPtr(16)}, Nullable: true},
+			{Name: "agent_id", Spec: datatype.Spec{Type: "text"}, Nullable: true},
+			{Name: "gone", Spec: datatype.Spec{Type: "int4"}, Nullable: true},
+		}},
+		{Name: "dropped"},
+	}
+	catalog := []Table{
+		{Name: "alerts", Columns: []Column{
+			{Name: "id", Spec: datatype.Spec{Type: "int8"}},
+			{Name: "severity", Spec: datatype.Spec{Type: "varchar", Length: intPtr(32)}, Nullable: true},
+			{Name: "agent_id", Spec: datatype.Spec{Type: "text"}},
+			{Name: "manual", Spec: datatype.Spec{Type: "bool"}, Nullable: true},
+		}},
+		{Name: "wazuh"},
+		{Name: "scheduled_message"},
+	}
+
+	issues := Compare(metadata, catalog)
+
+	want := []struct{ kind, table, column string }{
+		{NullableMismatch, "alerts", "agent_id"},
+		{MissingColumn, "alerts", "gone"},
+		{UnregisteredColumn, "alerts", "manual"},
+		{TypeMismatch, "alerts", "severity"},
+		{MissingTable, "dropped", ""},
+		{UnregisteredTable, "wazuh", ""},
+	}
+
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d: %+v", len(issues), len(want), issues)
+	}
+	for i, w := range want {
+		if issues[i].Kind != w.kind || issues[i].Table != w.table || issues[i].Column != w.column {
+			t.Errorf("issue %d = %s %s.%s, want %s %s.%s", i, issues[i].Kind, issues[i].Table, issues[i].Column, w.kind, w.table, w.column)
+		}
+	}
+}
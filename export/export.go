@@ -0,0 +1,92 @@
+// Package export writes rows of a user defined table as CSV, NDJSON or an
+// Excel workbook. Writers stream, every row goes to the underlying writer as
+// soon as it is written.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Writer writes a header of column names followed by rows with one value per
+// column. Close must be called to finish the file.
+type Writer interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []any) error
+	Close() error
+}
+
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+	New         func(w io.Writer, sheet string) Writer
+}
+
+var formats = map[string]Format{
+	"csv": {
+		Name:        "csv",
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   "csv",
+		New:         func(w io.Writer, _ string) Writer { return NewCSV(w) },
+	},
+	"ndjson": {
+		Name:        "ndjson",
+		ContentType: "application/x-ndjson",
+		Extension:   "ndjson",
+		New:         func(w io.Writer, _ string) Writer { return NewNDJSON(w) },
+	},
+	"xlsx": {
+		Name:        "xlsx",
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		Extension:   "xlsx",
+		New:         func(w io.Writer, sheet string) Writer { return NewXLSX(w, sheet) },
+	},
+}
+
+// Lookup returns the format called name.
+func Lookup(name string) (Format, bool) {
+	format, ok := formats[name]
+	return format, ok
+}
+
+// normalize turns a value scanned by database/sql into nil, bool, int64,
+// float64 or string. JSON documents and arrays are kept as they are.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case nil, bool, int64, float64, string, json.RawMessage, []any:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case json.Number:
+		return string(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// text renders a normalized value for formats without types. nil is "".
+func text(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return v
+	case json.RawMessage:
+		return string(v)
+	case []any:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+	return fmt.Sprint(value)
+}
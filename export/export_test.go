@@ -0,0 +1,107 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeAll(t *testing.T, w Writer, columns []string, rows ...[]any) {
+	t.Helper()
+	if err := w.WriteHeader(columns); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	writeAll(t, NewCSV(&buf), []string{"id", "name", "ok"},
+		[]any{int64(1), "a,b", true},
+		[]any{int64(2), nil, []byte("1.50")},
+		[]any{json.RawMessage(`{"a":1}`), []any{"x", nil}, nil},
+	)
+
+	want := "id,name,ok\n1,\"a,b\",true\n2,,1.50\n\"{\"\"a\"\":1}\",\"[\"\"x\"\",null]\",\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeAll(t, NewNDJSON(&buf), []string{"z", "a"},
+		[]any{int64(1), at},
+		[]any{nil, "x\ny"},
+		[]any{json.RawMessage(`{"b":[1]}`), []any{"t"}},
+	)
+
+	want := `{"z":1,"a":"2025-01-02T03:04:05Z"}` + "\n" + `{"z":null,"a":"x\ny"}` + "\n" + `{"z":{"b":[1]},"a":["t"]}` + "\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	writeAll(t, NewXLSX(&buf, "alerts/2025"), []string{"id", "note"},
+		[]any{int64(1), "<b>&\x01"},
+		[]any{int64(1 << 60), nil},
+	)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+
+		if err := xml.Unmarshal(content, new(any)); err != nil && err != io.EOF {
+			t.Fatalf("%s is not valid XML: %v", file.Name, err)
+		}
+		parts[file.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+
+	if !strings.Contains(parts["xl/workbook.xml"], `name="alerts_2025"`) {
+		t.Errorf("sheet name not sanitized: %s", parts["xl/workbook.xml"])
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c t="inlineStr"><is><t xml:space="preserve">note</t></is></c>`,
+		`<c><v>1</v></c>`,
+		`<t xml:space="preserve">&lt;b&gt;&amp;</t>`,
+		`<t xml:space="preserve">1152921504606846976</t>`,
+		`<c/>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %s:\n%s", want, sheet)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ndjsonWriter writes one JSON object per row. Keys follow the column order.
+type ndjsonWriter struct {
+	w       io.Writer
+	columns [][]byte
+	line    []byte
+}
+
+func NewNDJSON(w io.Writer) Writer {
+	return &ndjsonWriter{w: w}
+}
+
+func (n *ndjsonWriter) WriteHeader(columns []string) error {
+	n.columns = make([][]byte, len(columns))
+	for i, column := range columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		n.columns[i] = key
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) WriteRow(values []any) error {
+	n.line = append(n.line[:0], '{')
+	for i, value := range values {
+		if i > 0 {
+			n.line = append(n.line, ',')
+		}
+		n.line = append(n.line, n.columns[i]...)
+		n.line = append(n.line, ':')
+
+		encoded, err := json.Marshal(normalize(value))
+		if err != nil {
+			return err
+		}
+		n.line = append(n.line, encoded...)
+	}
+	n.line = append(n.line, '}', '\n')
+
+	_, err := n.w.Write(n.line)
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,188 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Parts of the workbook that don't depend on the data. Cells are written as
+// inline strings so no shared string table has to be kept in memory.
+const (
+	xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+
+	xlsxRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+
+	xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+
+	xlsxSheetStart = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd   = `</sheetData></worksheet>`
+
+	// Excel refuses cells longer than this
+	xlsxMaxCellLength = 32767
+)
+
+// xlsxWriter writes a workbook with a single sheet. The sheet is the last part
+// of the zip so its rows can be streamed.
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	name  string
+}
+
+func NewXLSX(w io.Writer, sheet string) Writer {
+	return &xlsxWriter{zip: zip.NewWriter(w), name: sheetName(sheet)}
+}
+
+func (x *xlsxWriter) WriteHeader(columns []string) error {
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/workbook.xml", strings.Replace(xlsxWorkbook, "%s", escapeXML(x.name), 1)},
+	}
+	for _, part := range parts {
+		w, err := x.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+
+	w, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	x.sheet = bufio.NewWriter(w)
+	x.sheet.WriteString(xlsxSheetStart)
+
+	header := make([]any, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	return x.WriteRow(header)
+}
+
+func (x *xlsxWriter) WriteRow(values []any) error {
+	x.sheet.WriteString("<row>")
+	for _, value := range values {
+		switch v := normalize(value).(type) {
+		case nil:
+			x.sheet.WriteString("<c/>")
+		case bool:
+			if v {
+				x.sheet.WriteString(`<c t="b"><v>1</v></c>`)
+			} else {
+				x.sheet.WriteString(`<c t="b"><v>0</v></c>`)
+			}
+		case int64:
+			// Excel numbers are doubles, larger integers would lose digits
+			if v > 1<<53 || v < -(1<<53) {
+				x.writeString(strconv.FormatInt(v, 10))
+			} else {
+				x.sheet.WriteString(`<c><v>` + strconv.FormatInt(v, 10) + `</v></c>`)
+			}
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				x.writeString(strconv.FormatFloat(v, 'g', -1, 64))
+			} else {
+				x.sheet.WriteString(`<c><v>` + strconv.FormatFloat(v, 'g', -1, 64) + `</v></c>`)
+			}
+		default:
+			x.writeString(text(v))
+		}
+	}
+	_, err := x.sheet.WriteString("</row>")
+	return err
+}
+
+func (x *xlsxWriter) writeString(value string) {
+	if len(value) > xlsxMaxCellLength {
+		value = truncateUTF8(value, xlsxMaxCellLength)
+	}
+	x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	x.sheet.WriteString(escapeXML(value))
+	x.sheet.WriteString(`</t></is></c>`)
+}
+
+func (x *xlsxWriter) Close() error {
+	if x.sheet == nil {
+		if err := x.WriteHeader(nil); err != nil {
+			return err
+		}
+	}
+	x.sheet.WriteString(xlsxSheetEnd)
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+// escapeXML escapes value for element text and attributes, dropping the
+// characters XML 1.0 can't represent.
+func escapeXML(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case r == '"':
+			b.WriteString("&quot;")
+		case r == '\t', r == '\n', r == '\r':
+			b.WriteRune(r)
+		case r < 0x20, r == 0xFFFE, r == 0xFFFF, r >= 0xD800 && r <= 0xDFFF:
+			// not allowed in XML
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// sheetName makes name a valid sheet name: at most 31 characters, none of
+// []:*?/\.
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		return "Sheet1"
+	}
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	return name
+}
+
+func truncateUTF8(value string, n int) string {
+	for n > 0 && n < len(value) && value[n]&0xC0 == 0x80 {
+		n--
+	}
+	return value[:n]
+}
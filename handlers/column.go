@@ -1,12 +1,14 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
-	"wolfscream/database"
+	"wolfscream/datatype"
+	"wolfscream/schemadoc"
+	"wolfscream/store"
+	"wolfscream/validator"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -14,20 +16,26 @@ import (
 // --------------------
 // Add Column
 // --------------------
-func AddColumn(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AddColumn(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	tableName := chi.URLParam(r, "table-name")
 
 	type AddColumnBody struct {
-		Name         string `json:"name" validate:"required,snakecase,min=1"`
-		Type         string `json:"type" validate:"required"`
-		Length       *int   `json:"length" validate:"omitempty,gte=1"`
-		DefaultValue any    `json:"default"`
+		Name         string   `json:"name" validate:"required,snakecase,min=1"`
+		Type         string   `json:"type" validate:"required"`
+		Length       *int     `json:"length" validate:"omitempty,gte=1"`
+		Precision    *int     `json:"precision"`
+		Scale        *int     `json:"scale"`
+		Values       []string `json:"values"`
+		DefaultValue any      `json:"default"`
+		columnConstraints
 	}
 
 	var body AddColumnBody
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
@@ -36,130 +44,97 @@ func AddColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var tableId int
+	if err := validator.Validate.Struct(body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status": "error",
+			"errors": validator.FormatError(err),
+		})
+		return
+	}
 
-	err := database.DB.QueryRow("SELECT id FROM user_defined_table WHERE name = $1", tableName).Scan(&tableId)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": fmt.Sprintf("Table %s does not exist", tableName),
-			})
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
+	if body.Name == idColumn {
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to query table: %v", err),
+			"message": fmt.Sprintf("Column name %s is reserved", idColumn),
 		})
 		return
 	}
 
-	tx, err := database.DB.Begin()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"status": "error", "message": "Failed to start transaction"})
+	spec := datatype.Spec{
+		Type:       body.Type,
+		Length:     body.Length,
+		Precision:  body.Precision,
+		Scale:      body.Scale,
+		EnumValues: body.Values,
+	}
+	if spec.Type == "enum" {
+		spec.EnumType = datatype.EnumTypeName(tableName, body.Name)
+	}
+
+	if err := datatype.Validate(spec); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
 		return
 	}
-	defer tx.Rollback()
 
-	queryParts := []string{
-		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s`, tableName, body.Name),
+	ctx := r.Context()
+
+	column := store.Column{Name: body.Name, Spec: spec}
+	if err := h.validateConstraints(ctx, column, body.columnConstraints); err != nil {
+		writeConstraintError(w, err)
+		return
 	}
 
-	switch body.Type {
-	case "int4":
-		queryParts = append(queryParts, "INTEGER")
-	case "int8":
-		queryParts = append(queryParts, "BIGINT")
-	case "varchar":
-		if body.Length != nil {
-			queryParts = append(queryParts, fmt.Sprintf("VARCHAR(%d)", *body.Length))
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
+	if _, err := schemadoc.DefaultText(spec, body.DefaultValue); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Invalid default value for column %s: %v", body.Name, err),
+		})
+		return
+	}
+
+	if _, err := h.store.Tables.Get(ctx, h.store.DB, tableName); err != nil {
+		if err == store.ErrNotFound {
+			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{
 				"status":  "error",
-				"message": "Length is required for type varchar",
+				"message": fmt.Sprintf("Table %s does not exist", tableName),
 			})
 			return
 		}
-	case "text":
-		queryParts = append(queryParts, "TEXT")
-	case "bool":
-		queryParts = append(queryParts, "BOOLEAN")
-	case "float4":
-		queryParts = append(queryParts, "REAL")
-	case "float8":
-		queryParts = append(queryParts, "DOUBLE PRECISION")
-	default:
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Unknown column type: %s", body.Type),
+			"message": fmt.Sprintf("Failed to query table: %v", err),
 		})
 		return
 	}
 
-	if body.DefaultValue != nil {
-		switch body.Type {
-		case "varchar", "text":
-			switch value := body.DefaultValue.(type) {
-			case string:
-				queryParts = append(queryParts, fmt.Sprintf(`DEFAULT '%s'`, strings.ReplaceAll(value, "'", "''")))
-			default:
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]string{
-					"status":  "error",
-					"message": fmt.Sprintf("default value for column %s must be string", body.Name),
-				})
-				return
-			}
-		case "int4", "int8", "float4", "float8":
-			switch value := body.DefaultValue.(type) {
-			case float64:
-				queryParts = append(queryParts, fmt.Sprintf("DEFAULT %.0f", value))
-			default:
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]string{
-					"status":  "error",
-					"message": fmt.Sprintf("default value for column %s must be numeric", body.Name),
-				})
-				return
-			}
-		case "bool":
-			switch value := body.DefaultValue.(type) {
-			case bool:
-				if value {
-					queryParts = append(queryParts, "DEFAULT true")
-				} else {
-					queryParts = append(queryParts, "DEFAULT false")
-				}
-			default:
-				json.NewEncoder(w).Encode(map[string]string{
-					"status":  "error",
-					"message": fmt.Sprintf("default value for column %s must be boolean", body.Name),
-				})
-				return
-			}
-		}
+	tx, err := h.store.DB.Begin(ctx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"status": "error", "message": "Failed to start transaction"})
+		return
 	}
+	defer tx.Rollback()
 
-	if _, err := tx.Exec(strings.Join(queryParts, " ")); err != nil {
+	if err := h.addColumn(ctx, tx, tableName, column, body.DefaultValue); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to add column: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
 
-	if _, err := tx.Exec("INSERT INTO user_defined_column (user_defined_table_id, name, type, length, default_value) VALUES ($1, $2, $3, $4, $5)", tableId, body.Name, body.Type, *body.Length, body.DefaultValue); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to insert into columns: %v", err),
-		})
+	if err := h.applyConstraints(ctx, tx, tableName, column, body.columnConstraints); err != nil {
+		writeConstraintError(w, err)
 		return
 	}
 
@@ -182,57 +157,43 @@ func AddColumn(w http.ResponseWriter, r *http.Request) {
 // Add Column End
 // --------------------
 
+// addColumn adds a column with the default checked by
+// schemadoc.DefaultText, see ColumnStore.Add.
+func (h *Handler) addColumn(ctx context.Context, tx store.DBTX, tableName string, column store.Column, defaultValue any) error {
+	defaultText, err := schemadoc.DefaultText(column.Spec, defaultValue)
+	if err != nil {
+		return newRequestError("Invalid default value for column %s: %v", column.Name, err)
+	}
+	return h.store.Columns.Add(ctx, tx, tableName, column, defaultText)
+}
+
+// setColumnDefault sets the default of a column, or drops it when value is
+// nil.
+func (h *Handler) setColumnDefault(ctx context.Context, tx store.DBTX, tableName string, column store.Column, value any) error {
+	defaultText, err := schemadoc.DefaultText(column.Spec, value)
+	if err != nil {
+		return newRequestError("Invalid default value for column %s: %v", column.Name, err)
+	}
+	return h.store.Columns.SetDefault(ctx, tx, tableName, column, defaultText)
+}
+
 // --------------------
 // List Columns
 // --------------------
-func ListColumns(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListColumns(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	tableName := chi.URLParam(r, "table-name")
 
-	query := `
-		SELECT 
-    		udc.name,
-    		udc.type,
-			udc.length,
-    		udc.default_value
-		FROM user_defined_column udc
-			JOIN user_defined_table udt ON udc.user_defined_table_id = udt.id
-		WHERE udt.name = $1;
-	`
-
-	rows, err := database.DB.Query(query, tableName)
+	columns, err := h.store.Columns.Describe(r.Context(), h.store.DB, tableName)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to query columns: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer rows.Close()
-
-	type Column struct {
-		Name         string `json:"name"`
-		Type         string `json:"type"`
-		Length       *int   `json:"length"`
-		DefaultValue any    `json:"default"`
-	}
-
-	columns := []Column{}
-
-	for rows.Next() {
-		var column Column
-		if err := rows.Scan(&column.Name, &column.Type, &column.Length, &column.DefaultValue); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": fmt.Sprintf("Failed to scan column: %v", err),
-			})
-			return
-		}
-		columns = append(columns, column)
-	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]any{
@@ -245,20 +206,26 @@ func ListColumns(w http.ResponseWriter, r *http.Request) {
 // List Columns End
 // --------------------
 
-// *
-func UpdateColumn(w http.ResponseWriter, r *http.Request) {
+// --------------------
+// Update Column
+// --------------------
+func (h *Handler) UpdateColumn(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	tableName := chi.URLParam(r, "table-name")
-	columnName := chi.URLParam(r, "column-name")
+	columnName := chi.URLParam(r, "column")
 
 	type UpdateColumnBody struct {
-		Name         *string `json:"name"`
+		Name         *string `json:"name" validate:"omitempty,snakecase"`
 		DefaultValue any     `json:"default_value"`
+		typeChange
+		columnConstraints
 	}
 
 	var body UpdateColumnBody
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
@@ -267,101 +234,123 @@ func UpdateColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var columnType string
+	if err := validator.Validate.Struct(body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status": "error",
+			"errors": validator.FormatError(err),
+		})
+		return
+	}
 
-	err := database.DB.
-		QueryRow("SELECT type FROM user_defined_columns WHERE name = $1", columnName).
-		Scan(
-			&columnType,
-		)
+	if body.Name != nil && *body.Name == idColumn {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Column name %s is reserved", idColumn),
+		})
+		return
+	}
+
+	ctx := r.Context()
+
+	columns, err := h.store.Columns.Load(ctx, h.store.DB, tableName)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": "Column not found",
-			})
-			return
-		}
+		writeTableColumnsError(w, tableName, err)
+		return
+	}
 
-		w.WriteHeader(http.StatusInternalServerError)
+	var column *store.Column
+	for i := range columns {
+		if columns[i].Name == columnName && !columns[i].Managed {
+			column = &columns[i]
+		}
+	}
+	if column == nil {
+		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to query column: %v", err),
+			"message": "Column not found",
 		})
 		return
 	}
 
-	tx, err := database.DB.Begin()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	// the rest of the update applies to the column as converted
+	converted := *column
+	if body.typeChange.requested() {
+		converted.Spec, err = body.typeChange.target(tableName, *column)
+		if err != nil {
+			writeConstraintError(w, err)
+			return
+		}
+	} else if dryRun {
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": "Failed to start transaction",
+			"message": "dry_run only applies to type changes",
 		})
 		return
 	}
-	defer tx.Rollback()
 
-	queryParts := []string{
-		fmt.Sprintf("ALTER TABLE %s", tableName),
+	if err := h.validateConstraints(ctx, converted, body.columnConstraints); err != nil {
+		writeConstraintError(w, err)
+		return
 	}
 
+	var defaultValue *string
 	if body.DefaultValue != nil {
-		switch columnType {
-		case "varchar", "text":
-			switch value := body.DefaultValue.(type) {
-			case string:
-				queryParts = append(queryParts, fmt.Sprintf(`ALTER COLUMN %s SET DEFAULT '%s'`, columnName, strings.ReplaceAll(value, "'", "''")))
-			default:
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]string{
-					"status":  "error",
-					"message": fmt.Sprintf("default value for column %s must be string", *body.Name),
-				})
-				return
-			}
-		case "int4", "int8", "float4", "float8":
-			switch value := body.DefaultValue.(type) {
-			case float64:
-				queryParts = append(queryParts, fmt.Sprintf("ALTER COLUMN %s SET DEFAULT %f", columnName, value))
-			default:
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]string{
-					"status":  "error",
-					"message": fmt.Sprintf("default value for column %s must be numeric", *body.Name),
-				})
-				return
-			}
-		case "bool":
-			switch value := body.DefaultValue.(type) {
-			case bool:
-				if value {
-					queryParts = append(queryParts, fmt.Sprintf("ALTER COLUMN %s SET DEFAULT true", columnName))
-				} else {
-					queryParts = append(queryParts, fmt.Sprintf("ALTER COLUMN %s SET DEFAULT false", columnName))
-				}
-			default:
-				json.NewEncoder(w).Encode(map[string]string{
-					"status":  "error",
-					"message": fmt.Sprintf("default value for column %s must be boolean", *body.Name),
-				})
-				return
-			}
+		if _, err := datatype.Literal(converted.Spec, body.DefaultValue); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "error",
+				"message": fmt.Sprintf("Invalid default value for column %s: %v", columnName, err),
+			})
+			return
 		}
+
+		coerced, _ := datatype.Coerce(converted.Spec, body.DefaultValue)
+		text := datatype.Text(coerced)
+		defaultValue = &text
 	}
 
-	if _, err := tx.Exec(strings.Join(queryParts, " ")); err != nil {
+	newName := columnName
+	if body.Name != nil {
+		newName = *body.Name
+	}
+
+	tx, err := h.store.DB.Begin(ctx)
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to update column: %v", err),
+			"message": "Failed to start transaction",
 		})
 		return
 	}
+	defer tx.Rollback()
 
-	if body.Name != nil && *body.Name != columnName {
-		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", tableName, columnName, *body.Name)); err != nil {
+	var report *conversionReport
+	if body.typeChange.requested() {
+		report, err = h.changeColumnType(ctx, tx, tableName, *column, converted.Spec, body.OnInvalid, dryRun)
+		if err != nil {
+			writeConstraintError(w, err)
+			return
+		}
+
+		if dryRun {
+			json.NewEncoder(w).Encode(map[string]any{
+				"status":  "success",
+				"message": "Dry run, the column was not changed",
+				"data":    report,
+			})
+			return
+		}
+	}
+
+	if defaultValue != nil {
+		if err := h.store.Columns.SetDefault(ctx, tx, tableName, converted, defaultValue); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{
 				"status":  "error",
@@ -371,20 +360,22 @@ func UpdateColumn(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if _, err := tx.Exec(`
-		UPDATE columns SET 
-	     	name = $1,
-	    	default_value = $2
-	    WHERE name = $3`,
-		*body.Name, body.DefaultValue, tableName); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to update column: %v", err),
-		})
+	if err := h.applyConstraints(ctx, tx, tableName, converted, body.columnConstraints); err != nil {
+		writeConstraintError(w, err)
 		return
 	}
 
+	if newName != columnName {
+		if err := h.store.Columns.Rename(ctx, tx, tableName, columnName, newName); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -394,51 +385,75 @@ func UpdateColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
+	json.NewEncoder(w).Encode(map[string]any{
 		"status":  "success",
 		"message": "Column updated successfully",
+		"data":    report,
 	})
 }
 
+// --------------------
+// Update Column End
+// --------------------
+
 // --------------------
 // Delete Column
 // --------------------
-func DeleteColumn(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteColumn(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	columnName := chi.URLParam(r, "column-name")
+	columnName := chi.URLParam(r, "column")
 	tableName := chi.URLParam(r, "table-name")
 
-	tx, err := database.DB.Begin()
+	if columnName == idColumn {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Column %s is reserved and can't be deleted", idColumn),
+		})
+		return
+	}
+
+	ctx := r.Context()
+
+	columns, err := h.store.Columns.Load(ctx, h.store.DB, tableName)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeTableColumnsError(w, tableName, err)
+		return
+	}
+
+	// only registered columns are dropped, never other columns of the table
+	found := false
+	for _, column := range columns {
+		if column.Name == columnName && !column.Managed {
+			found = true
+		}
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": "Failed to start transaction",
+			"message": "Column not found",
 		})
 		return
 	}
-	defer tx.Rollback()
 
-	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableName, columnName)); err != nil {
+	tx, err := h.store.DB.Begin(ctx)
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to drop column: %v", err),
+			"message": "Failed to start transaction",
 		})
 		return
 	}
+	defer tx.Rollback()
 
-	deleteQuery := `
-		DELETE FROM user_defined_column
-		WHERE user_defined_table_id = (SELECT id FROM user_defined_table WHERE name = $1)
-		AND name = $2
-	`
-	if _, err := tx.Exec(deleteQuery, tableName, columnName); err != nil {
+	if err := h.store.Columns.Drop(ctx, tx, tableName, columnName); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to delete column: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
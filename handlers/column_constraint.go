@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"wolfscream/check"
+	"wolfscream/datatype"
+	"wolfscream/store"
+
+	"github.com/lib/pq"
+)
+
+// maxConflictValues limits the duplicate values reported for a unique
+// constraint.
+const maxConflictValues = 10
+
+var onDeleteActions = map[string]string{
+	"restrict": "RESTRICT",
+	"cascade":  "CASCADE",
+	"set null": "SET NULL",
+}
+
+// columnConstraints are the constraint fields of the add and update column
+// bodies. nil leaves a constraint as it is, an empty check or references
+// removes it.
+type columnConstraints struct {
+	Nullable   *bool   `json:"nullable"`
+	Unique     *bool   `json:"unique"`
+	Check      *string `json:"check"`
+	References *string `json:"references"`
+	OnDelete   *string `json:"on_delete"`
+	// Backfill is written to the rows without a value before NOT NULL is
+	// set.
+	Backfill any `json:"backfill"`
+}
+
+// constraintConflict is returned when existing rows violate a constraint
+// that is being added. It is answered with 409.
+type constraintConflict struct {
+	Constraint string `json:"constraint"`
+	Message    string `json:"message"`
+	Rows       int    `json:"rows"`
+	Values     []any  `json:"values,omitempty"`
+}
+
+func (e *constraintConflict) Error() string {
+	return e.Message
+}
+
+// validateConstraints checks the parts of c that don't need the table data.
+func (h *Handler) validateConstraints(ctx context.Context, column store.Column, c columnConstraints) error {
+	if c.Check != nil && *c.Check != "" {
+		if column.Type == "jsonb" || column.Type == "text[]" {
+			return newRequestError("check expressions are not supported on %s columns", column.Type)
+		}
+		if _, err := check.Parse(*c.Check); err != nil {
+			return newRequestError("Invalid check: %v", err)
+		}
+	}
+
+	if c.OnDelete != nil && c.References == nil {
+		return newRequestError("on_delete requires references")
+	}
+
+	if c.References != nil && *c.References != "" {
+		if column.Type != "int8" && column.Type != "int4" {
+			return newRequestError("Only int8 and int4 columns can reference a table")
+		}
+
+		onDelete := "restrict"
+		if c.OnDelete != nil {
+			onDelete = *c.OnDelete
+		}
+		if _, ok := onDeleteActions[onDelete]; !ok {
+			return newRequestError("on_delete must be restrict, cascade or set null")
+		}
+		if onDelete == "set null" && c.Nullable != nil && !*c.Nullable {
+			return newRequestError("on_delete set null requires a nullable column")
+		}
+
+		target, err := h.store.Columns.Load(ctx, h.store.DB, *c.References)
+		if err == store.ErrNotFound {
+			return newRequestError("Table %s does not exist", *c.References)
+		}
+		if err != nil {
+			return err
+		}
+		if !hasIdColumn(target) {
+			return newRequestError("Table %s has no id column", *c.References)
+		}
+	}
+
+	if c.Backfill != nil {
+		if c.Nullable == nil || *c.Nullable {
+			return newRequestError("backfill only applies when nullable is set to false")
+		}
+		if _, err := datatype.Coerce(column.Spec, c.Backfill); err != nil {
+			return newRequestError("Invalid backfill value: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// constraintName returns the name of a constraint created for a column.
+func constraintName(table string, column string, suffix string) string {
+	return datatype.Identifier(suffix, table, column)
+}
+
+// applyConstraints adds and removes the constraints of a column inside tx
+// and records them in user_defined_column. Before a constraint is added the
+// existing rows are checked so the client gets the rows that violate it
+// instead of a bare Postgres error.
+func (h *Handler) applyConstraints(ctx context.Context, tx store.DBTX, table string, column store.Column, c columnConstraints) error {
+	if c.Nullable == nil && c.Unique == nil && c.Check == nil && c.References == nil {
+		return nil
+	}
+
+	schema := h.store.Schema
+
+	// keep writers out between the checks and the ALTER TABLE
+	if err := schema.LockTable(ctx, tx, table); err != nil {
+		return err
+	}
+
+	if c.Nullable != nil && *c.Nullable {
+		if err := schema.SetNullable(ctx, tx, table, column.Name, true); err != nil {
+			return err
+		}
+	}
+
+	if c.Nullable != nil && !*c.Nullable {
+		if c.Backfill != nil {
+			literal, err := datatype.Literal(column.Spec, c.Backfill)
+			if err != nil {
+				return newRequestError("Invalid backfill value: %v", err)
+			}
+			if err := schema.Backfill(ctx, tx, table, column.Name, literal); err != nil {
+				return err
+			}
+		}
+
+		n, err := schema.CountNulls(ctx, tx, table, column.Name)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return &constraintConflict{
+				Constraint: "not_null",
+				Message:    fmt.Sprintf("%d rows have no value for %s, set a default or a backfill value", n, column.Name),
+				Rows:       n,
+			}
+		}
+
+		if err := schema.SetNullable(ctx, tx, table, column.Name, false); err != nil {
+			return err
+		}
+	}
+
+	if c.Unique != nil {
+		if err := schema.DropColumnConstraints(ctx, tx, table, column.Name, "u"); err != nil {
+			return err
+		}
+	}
+
+	if c.Unique != nil && *c.Unique {
+		values, rows, err := schema.Duplicates(ctx, tx, table, column.Name, maxConflictValues)
+		if err != nil {
+			return err
+		}
+		if len(values) > 0 {
+			return &constraintConflict{
+				Constraint: "unique",
+				Message:    fmt.Sprintf("%s has duplicate values", column.Name),
+				Rows:       rows,
+				Values:     toAnySlice(values),
+			}
+		}
+
+		if err := schema.AddUnique(ctx, tx, table, column.Name, constraintName(table, column.Name, "key")); err != nil {
+			return err
+		}
+	}
+
+	if c.Check != nil {
+		if err := schema.DropColumnConstraints(ctx, tx, table, column.Name, "c"); err != nil {
+			return err
+		}
+	}
+
+	if c.Check != nil && *c.Check != "" {
+		conditions, err := check.Parse(*c.Check)
+		if err != nil {
+			return newRequestError("Invalid check: %v", err)
+		}
+
+		expression, err := check.SQL(conditions, pq.QuoteIdentifier(column.Name), func(value string) (string, error) {
+			return datatype.TextLiteral(column.Spec, value)
+		})
+		if err != nil {
+			return newRequestError("Invalid check: %v", err)
+		}
+
+		n, err := schema.CountViolations(ctx, tx, table, expression)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return &constraintConflict{
+				Constraint: "check",
+				Message:    fmt.Sprintf("%d rows don't satisfy the check", n),
+				Rows:       n,
+			}
+		}
+
+		if err := schema.AddCheck(ctx, tx, table, constraintName(table, column.Name, "check"), expression); err != nil {
+			return err
+		}
+	}
+
+	onDelete := ""
+	if c.References != nil {
+		if err := schema.DropColumnConstraints(ctx, tx, table, column.Name, "f"); err != nil {
+			return err
+		}
+	}
+
+	if c.References != nil && *c.References != "" {
+		onDelete = "restrict"
+		if c.OnDelete != nil {
+			onDelete = *c.OnDelete
+		}
+
+		n, err := schema.CountDangling(ctx, tx, table, column.Name, *c.References)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return &constraintConflict{
+				Constraint: "references",
+				Message:    fmt.Sprintf("%d rows reference ids that don't exist in %s", n, *c.References),
+				Rows:       n,
+			}
+		}
+
+		if err := schema.AddForeignKey(
+			ctx, tx, table, column.Name, constraintName(table, column.Name, "fkey"), *c.References, onDeleteActions[onDelete],
+		); err != nil {
+			return err
+		}
+	}
+
+	return h.store.Columns.SetConstraints(ctx, tx, table, column.Name, store.Constraints{
+		Nullable:   c.Nullable,
+		Unique:     c.Unique,
+		Check:      c.Check,
+		References: c.References,
+		OnDelete:   onDelete,
+	})
+}
+
+// writeConstraintError answers a failed validateConstraints,
+// applyConstraints or changeColumnType.
+func writeConstraintError(w http.ResponseWriter, err error) {
+	var conflict *constraintConflict
+	if errors.As(err, &conflict) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":  "error",
+			"message": conflict.Message,
+			"data":    conflict,
+		})
+		return
+	}
+
+	if _, ok := err.(*requestError); ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(queryErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "error",
+		"message": fmt.Sprintf("Failed to alter column: %v", err),
+	})
+}
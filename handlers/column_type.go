@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"wolfscream/datatype"
+	"wolfscream/store"
+)
+
+// maxConversionSamples limits the values reported as failing to convert.
+const maxConversionSamples = 10
+
+const (
+	onInvalidFail = "fail"
+	onInvalidNull = "null"
+)
+
+// typeChange is the type part of the update column body. Fields left out keep
+// their current value as long as the type stays the same.
+type typeChange struct {
+	Type      *string  `json:"type"`
+	Length    *int     `json:"length"`
+	Precision *int     `json:"precision"`
+	Scale     *int     `json:"scale"`
+	Values    []string `json:"values"`
+	// OnInvalid is fail, the default, to refuse the change when a value
+	// can't be converted, or null to store NULL instead.
+	OnInvalid string `json:"on_invalid"`
+}
+
+func (c typeChange) requested() bool {
+	return c.Type != nil || c.Length != nil || c.Precision != nil || c.Scale != nil || c.Values != nil
+}
+
+// target returns the spec of the column after the change.
+func (c typeChange) target(table string, column store.Column) (datatype.Spec, error) {
+	spec := column.Spec
+	if c.Type != nil && *c.Type != spec.Type {
+		spec = datatype.Spec{Type: *c.Type}
+	}
+	if c.Length != nil {
+		spec.Length = c.Length
+	}
+	if c.Precision != nil {
+		spec.Precision = c.Precision
+	}
+	if c.Scale != nil {
+		spec.Scale = c.Scale
+	}
+	if c.Values != nil {
+		spec.EnumValues = c.Values
+	}
+	if spec.Type == "enum" {
+		spec.EnumType = datatype.EnumTypeName(table, column.Name)
+	}
+
+	if err := datatype.Validate(spec); err != nil {
+		return spec, newRequestError("Invalid type: %v", err)
+	}
+	if c.OnInvalid != "" && c.OnInvalid != onInvalidFail && c.OnInvalid != onInvalidNull {
+		return spec, newRequestError("on_invalid must be fail or null")
+	}
+	return spec, nil
+}
+
+// conversionReport tells how the rows of a column convert to a new type.
+type conversionReport struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Rows    int      `json:"rows"`
+	Invalid int      `json:"invalid"`
+	Samples []string `json:"samples"`
+}
+
+// changeColumnType converts a column to the type to inside tx, through the
+// text representation of its values, and updates its metadata. Values that
+// don't convert fail the change, or become NULL when onInvalid is null. With
+// dryRun the rows are only counted and the column is left alone.
+//
+// The default and the check of the column are carried over when they are
+// still valid for the new type, otherwise the default is dropped and the
+// check reported as a conflict. Values are tested with pg_input_is_valid,
+// which needs Postgres 16.
+func (h *Handler) changeColumnType(ctx context.Context, tx store.DBTX, table string, column store.Column, to datatype.Spec, onInvalid string, dryRun bool) (*conversionReport, error) {
+	current, err := h.store.Columns.Get(ctx, tx, table, column.Name)
+	if err != nil {
+		return nil, err
+	}
+	nullable := current.Nullable
+	defaultValue, hasDefault := current.DefaultValue.(string)
+
+	if current.References != nil && to.Type != "int8" && to.Type != "int4" {
+		return nil, newRequestError("Column %s references %s and must stay int8 or int4", column.Name, current.References.Table)
+	}
+
+	if err := h.store.Schema.LockTable(ctx, tx, table); err != nil {
+		return nil, err
+	}
+
+	// the new enum type gets a temporary name, the old one may still use the
+	// final name
+	target := to
+	if to.Type == "enum" {
+		target.EnumType = datatype.ConversionEnumTypeName(table, column.Name)
+		if err := h.store.Schema.CreateEnum(ctx, tx, target); err != nil {
+			return nil, err
+		}
+	}
+	targetType := datatype.SQL(target)
+
+	report := &conversionReport{From: datatype.SQL(column.Spec), To: datatype.SQL(to)}
+	report.Rows, report.Invalid, report.Samples, err = h.store.Schema.CheckConversion(ctx, tx, table, column.Name, targetType, maxConversionSamples)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	if report.Invalid > 0 && (onInvalid != onInvalidNull || !nullable) {
+		message := fmt.Sprintf("%d rows can't be converted to %s", report.Invalid, report.To)
+		if onInvalid == onInvalidNull {
+			message += " and the column doesn't accept NULL"
+		}
+		return report, &constraintConflict{
+			Constraint: "type",
+			Message:    message,
+			Rows:       report.Invalid,
+			Values:     toAnySlice(report.Samples),
+		}
+	}
+
+	// the default and the check are typed, they are set again after the
+	// conversion
+	if err := h.store.Schema.DropColumnConstraints(ctx, tx, table, column.Name, "c"); err != nil {
+		return nil, err
+	}
+	if err := h.store.Schema.SetDefault(ctx, tx, table, column.Name, nil); err != nil {
+		return nil, err
+	}
+
+	if err := h.store.Schema.ConvertColumn(ctx, tx, table, column.Name, targetType, onInvalid == onInvalidNull); err != nil {
+		return nil, err
+	}
+
+	if column.Type == "enum" {
+		if err := h.store.Schema.DropEnum(ctx, tx, column.EnumType); err != nil {
+			return nil, err
+		}
+	}
+	if to.Type == "enum" {
+		if err := h.store.Schema.RenameEnum(ctx, tx, target.EnumType, to.EnumType); err != nil {
+			return nil, err
+		}
+	}
+
+	var newDefault *string
+	if hasDefault {
+		if literal, err := datatype.TextLiteral(to, defaultValue); err == nil {
+			if err := h.store.Schema.SetDefault(ctx, tx, table, column.Name, &literal); err != nil {
+				return nil, err
+			}
+			newDefault = &defaultValue
+		}
+	}
+
+	if err := h.store.Columns.SetType(ctx, tx, table, column.Name, to, newDefault); err != nil {
+		return nil, err
+	}
+
+	if current.Check != nil {
+		converted := store.Column{Name: column.Name, Spec: to}
+		if err := h.applyConstraints(ctx, tx, table, converted, columnConstraints{Check: current.Check}); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func toAnySlice(values []string) []any {
+	result := make([]any, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"wolfscream/rule"
+	"wolfscream/store"
+)
+
+const (
+	defaultDataLimit = 100
+	maxDataLimit     = 1000
+)
+
+// parseDataQuery reads the query string accepted by the data API:
+//
+//	filter  rule expression, e.g. "severity == high; agent_id != 001"
+//	sort    comma separated columns, "-" prefix for descending
+//	fields  comma separated columns to return
+//	limit   page size
+//	offset  rows to skip, or
+//	cursor  next_cursor of the previous page
+func parseDataQuery(table string, tableColumns []store.Column, values url.Values) (*store.DataQuery, error) {
+	columns := map[string]store.Column{}
+	for _, column := range tableColumns {
+		columns[column.Name] = column
+	}
+
+	q := &store.DataQuery{
+		Table:   table,
+		Columns: columns,
+		Limit:   defaultDataLimit,
+	}
+
+	conditions, err := rule.Parse(values.Get("filter"))
+	if err != nil {
+		return nil, newRequestError("Invalid filter: %v", err)
+	}
+	for _, condition := range conditions {
+		if _, ok := columns[condition.Column]; !ok {
+			return nil, newRequestError("Unknown column in filter: %s", condition.Column)
+		}
+	}
+	q.Conditions = conditions
+
+	q.Fields, err = parseFields(tableColumns, values.Get("fields"))
+	if err != nil {
+		return nil, err
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		for key := range strings.SplitSeq(sort, ",") {
+			key = strings.TrimSpace(key)
+			desc := strings.HasPrefix(key, "-")
+			key = strings.TrimPrefix(key, "-")
+			if _, ok := columns[key]; !ok {
+				return nil, newRequestError("Unknown column in sort: %s", key)
+			}
+			q.Sort = append(q.Sort, store.SortKey{Column: key, Desc: desc})
+		}
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		q.Limit, err = strconv.Atoi(limit)
+		if err != nil || q.Limit < 1 || q.Limit > maxDataLimit {
+			return nil, newRequestError("limit must be between 1 and %d", maxDataLimit)
+		}
+	}
+
+	offset, cursor := values.Get("offset"), values.Get("cursor")
+	if offset != "" && cursor != "" {
+		return nil, newRequestError("offset and cursor can't be used together")
+	}
+
+	if offset != "" {
+		q.Offset, err = strconv.Atoi(offset)
+		if err != nil || q.Offset < 0 {
+			return nil, newRequestError("offset must be a positive number")
+		}
+	}
+
+	if cursor != "" {
+		q.Cursor, err = decodeCursor(cursor)
+		if err != nil || q.Cursor.Sort != q.SortSpec() || len(q.Cursor.Values) != len(q.Sort) {
+			return nil, newRequestError("Invalid cursor")
+		}
+	}
+
+	return q, nil
+}
+
+func encodeCursor(cursor store.DataCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*store.DataCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor store.DataCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wolfscream/datatype"
+	"wolfscream/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// writeTableColumnsError answers a failed ColumnStore.Load.
+func writeTableColumnsError(w http.ResponseWriter, table string, err error) {
+	if err == store.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Table %s does not exist", table),
+		})
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "error",
+		"message": err.Error(),
+	})
+}
+
+// rowIdParam reads the {id} URL parameter.
+func rowIdParam(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return 0, newRequestError("Invalid id: %s", chi.URLParam(r, "id"))
+	}
+	return id, nil
+}
+
+// decodeRowBody decodes a JSON object keeping numbers exact, so int8 values
+// don't go through float64.
+func decodeRowBody(r *http.Request) (map[string]any, error) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+
+	var body map[string]any
+	if err := decoder.Decode(&body); err != nil {
+		return nil, newRequestError("Invalid JSON")
+	}
+	return body, nil
+}
+
+// coerceValues converts the values of body to the types of the columns. It
+// rejects unknown columns and the managed id column.
+func coerceValues(columns []store.Column, body map[string]any) (map[string]any, error) {
+	byName := map[string]store.Column{}
+	for _, column := range columns {
+		byName[column.Name] = column
+	}
+
+	values := map[string]any{}
+	for key, value := range body {
+		column, ok := byName[key]
+		if !ok {
+			return nil, newRequestError("Unknown column: %s", key)
+		}
+		if column.Managed {
+			return nil, newRequestError("Column %s is managed and can't be written", key)
+		}
+
+		coerced, err := datatype.Coerce(column.Spec, value)
+		if err != nil {
+			return nil, newRequestError("Invalid value for column %s: %v", key, err)
+		}
+		values[key] = coerced
+	}
+
+	return values, nil
+}
+
+// dropMatchingId removes the id from a PUT or PATCH body when it is the id of
+// the row being written, so clients can send back a row they fetched.
+func dropMatchingId(body map[string]any, id int64) {
+	value, ok := body[idColumn]
+	if !ok {
+		return
+	}
+	if n, ok := value.(json.Number); ok && n.String() == strconv.FormatInt(id, 10) {
+		delete(body, idColumn)
+	}
+}
+
+func parseFields(columns []store.Column, fields string) ([]string, error) {
+	result := []string{}
+
+	if fields == "" {
+		for _, column := range columns {
+			result = append(result, column.Name)
+		}
+		return result, nil
+	}
+
+	known := map[string]bool{}
+	for _, column := range columns {
+		known[column.Name] = true
+	}
+
+	for field := range strings.SplitSeq(fields, ",") {
+		field = strings.TrimSpace(field)
+		if !known[field] {
+			return nil, newRequestError("Unknown column in fields: %s", field)
+		}
+		result = append(result, field)
+	}
+
+	return result, nil
+}
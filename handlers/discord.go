@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"wolfscream/discord"
 	"wolfscream/models"
 
 	"github.com/bwmarrin/discordgo"
@@ -15,10 +14,16 @@ import (
 // --------------------
 // List Discord Guilds
 // --------------------
-func ListDiscordGuilds(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListDiscordGuilds(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	guilds, err := discord.DiscordBot.UserGuilds(100, "", "", false)
+	session, err := h.discord.Session()
+	if err != nil {
+		writePlatformError(w, "Discord", err)
+		return
+	}
+
+	guilds, err := session.UserGuilds(100, "", "", false, discordgo.WithContext(r.Context()))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -69,12 +74,18 @@ func ListDiscordGuilds(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // List Discord Channels
 // --------------------
-func ListDiscordChannels(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListDiscordChannels(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	guildID := chi.URLParam(r, "guildId")
 
-	channels, err := discord.DiscordBot.GuildChannels(guildID)
+	session, err := h.discord.Session()
+	if err != nil {
+		writePlatformError(w, "Discord", err)
+		return
+	}
+
+	channels, err := session.GuildChannels(guildID, discordgo.WithContext(r.Context()))
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
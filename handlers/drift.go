@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"wolfscream/datatype"
+	"wolfscream/drift"
+	"wolfscream/store"
+	"wolfscream/tablestream"
+)
+
+// --------------------
+// Get Drift
+// --------------------
+
+// GetDrift lists the differences between the registered tables and columns
+// and the tables of the database.
+func (h *Handler) GetDrift(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	metadata, err := h.store.Tables.Metadata(r.Context(), h.store.DB)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	catalog, err := h.store.Schema.Catalog(r.Context(), h.store.DB)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	issues := drift.Compare(metadata, catalog)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"issues": issues,
+			"count":  len(issues),
+		},
+	})
+}
+
+// --------------------
+// Get Drift End
+// --------------------
+
+// --------------------
+// Repair Drift
+// --------------------
+
+// RepairDrift fixes drift in the metadata, never in the tables themselves.
+// With adopt unregistered tables and columns are registered and mismatching
+// columns take the type and nullability found in the catalog. With prune the
+// metadata of tables and columns that don't exist is removed. tables limits
+// the repair to some tables.
+func (h *Handler) RepairDrift(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	type RepairDriftBody struct {
+		Adopt  bool     `json:"adopt"`
+		Prune  bool     `json:"prune"`
+		Tables []string `json:"tables"`
+	}
+
+	var body RepairDriftBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Invalid request body",
+		})
+		return
+	}
+
+	if !body.Adopt && !body.Prune {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Set adopt, prune or both",
+		})
+		return
+	}
+
+	ctx := r.Context()
+
+	tx, err := h.store.DB.Begin(ctx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Failed to start transaction",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// keep concurrent metadata changes out until the repair is committed
+	if err := h.store.Schema.LockMetadata(ctx, tx); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Failed to lock metadata: %v", err),
+		})
+		return
+	}
+
+	metadata, err := h.store.Tables.Metadata(ctx, tx)
+	var catalog []drift.Table
+	if err == nil {
+		catalog, err = h.store.Schema.Catalog(ctx, tx)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	catalogTables := map[string]drift.Table{}
+	for _, table := range catalog {
+		catalogTables[table.Name] = table
+	}
+
+	applied := []drift.Issue{}
+	skipped := []skippedIssue{}
+
+	for _, issue := range drift.Compare(metadata, catalog) {
+		if len(body.Tables) > 0 && !slices.Contains(body.Tables, issue.Table) {
+			continue
+		}
+
+		var err error
+		switch {
+		case issue.Kind == drift.UnregisteredTable && body.Adopt:
+			var columns []skippedIssue
+			columns, err = h.adoptTable(ctx, tx, catalogTables[issue.Table])
+			skipped = append(skipped, columns...)
+		case issue.Kind == drift.UnregisteredColumn && body.Adopt:
+			if validateErr := datatype.Validate(*issue.Catalog); validateErr != nil {
+				skipped = append(skipped, skippedIssue{Issue: issue, Reason: validateErr.Error()})
+				continue
+			}
+			err = h.registerColumn(ctx, tx, issue.Table, findColumn(catalogTables[issue.Table], issue.Column))
+		case issue.Kind == drift.TypeMismatch && body.Adopt:
+			if validateErr := datatype.Validate(*issue.Catalog); validateErr != nil {
+				skipped = append(skipped, skippedIssue{Issue: issue, Reason: validateErr.Error()})
+				continue
+			}
+			err = h.syncColumn(ctx, tx, issue.Table, findColumn(catalogTables[issue.Table], issue.Column))
+		case issue.Kind == drift.NullableMismatch && body.Adopt:
+			err = h.syncColumn(ctx, tx, issue.Table, findColumn(catalogTables[issue.Table], issue.Column))
+		case issue.Kind == drift.MissingTable && body.Prune:
+			err = h.store.Tables.Unregister(ctx, tx, issue.Table)
+		case issue.Kind == drift.MissingColumn && body.Prune:
+			err = h.store.Columns.Unregister(ctx, tx, issue.Table, issue.Column)
+		default:
+			continue
+		}
+
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "error",
+				"message": fmt.Sprintf("Failed to repair %s: %v", issue.Message, err),
+			})
+			return
+		}
+		applied = append(applied, issue)
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Failed to commit transaction",
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  "success",
+		"message": fmt.Sprintf("%d issues repaired", len(applied)),
+		"data": map[string]any{
+			"applied": applied,
+			"skipped": skipped,
+		},
+	})
+}
+
+// --------------------
+// Repair Drift End
+// --------------------
+
+// skippedIssue is an issue the repair left alone and why.
+type skippedIssue struct {
+	drift.Issue
+	Reason string `json:"reason"`
+}
+
+func findColumn(table drift.Table, name string) drift.Column {
+	for _, column := range table.Columns {
+		if column.Name == name {
+			return column
+		}
+	}
+	return drift.Column{Name: name}
+}
+
+// adoptTable registers an existing table and the columns whose type is
+// supported, and installs the change trigger CreateTable installs.
+func (h *Handler) adoptTable(ctx context.Context, tx store.DBTX, table drift.Table) ([]skippedIssue, error) {
+	skipped := []skippedIssue{}
+
+	if err := h.store.Tables.Register(ctx, tx, table.Name, nil); err != nil {
+		return nil, err
+	}
+
+	for _, column := range table.Columns {
+		if column.Name == drift.IdColumn {
+			continue
+		}
+		if err := datatype.Validate(column.Spec); err != nil {
+			skipped = append(skipped, skippedIssue{
+				Issue: drift.Issue{
+					Kind:    drift.UnregisteredColumn,
+					Table:   table.Name,
+					Column:  column.Name,
+					Message: fmt.Sprintf("column %s.%s exists but isn't registered", table.Name, column.Name),
+					Catalog: &column.Spec,
+				},
+				Reason: err.Error(),
+			})
+			continue
+		}
+		if err := h.registerColumn(ctx, tx, table.Name, column); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tablestream.InstallTrigger(ctx, tx, table.Name); err != nil {
+		return nil, err
+	}
+
+	return skipped, nil
+}
+
+func (h *Handler) registerColumn(ctx context.Context, tx store.DBTX, table string, column drift.Column) error {
+	return h.store.Columns.Register(ctx, tx, table, store.Column{Name: column.Name, Spec: column.Spec}, column.Nullable)
+}
+
+func (h *Handler) syncColumn(ctx context.Context, tx store.DBTX, table string, column drift.Column) error {
+	return h.store.Columns.Sync(ctx, tx, table, store.Column{Name: column.Name, Spec: column.Spec}, column.Nullable)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// requestError is returned by helpers when the request itself is invalid and
+// has to be answered with 400.
+type requestError struct {
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func newRequestError(format string, args ...any) error {
+	return &requestError{message: fmt.Sprintf(format, args...)}
+}
+
+// queryErrorStatus answers 400 for errors caused by values the client sent,
+// such as a filter value that doesn't fit the column type, 409 for values
+// violating a constraint and 504 for queries canceled by the request timeout.
+func queryErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code.Name() == "query_canceled" {
+			return http.StatusGatewayTimeout
+		}
+		switch pqErr.Code.Class() {
+		case "22":
+			return http.StatusBadRequest
+		case "23":
+			return http.StatusConflict
+		}
+	}
+	return http.StatusInternalServerError
+}
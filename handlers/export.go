@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"wolfscream/export"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// --------------------
+// Export Data
+// --------------------
+
+// ExportData streams the rows of a table as csv, ndjson or xlsx. It accepts
+// the filter, sort and fields parameters of GetData, pagination parameters
+// are ignored and every matching row is exported.
+//
+// Rows are written as they are read from the database. An error after the
+// first byte was sent can't be reported anymore, the response is aborted so
+// the client doesn't mistake a truncated file for a complete one.
+func (h *Handler) ExportData(w http.ResponseWriter, r *http.Request) {
+	table := chi.URLParam(r, "table-name")
+
+	values := r.URL.Query()
+	formatName := values.Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+
+	format, ok := export.Lookup(formatName)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Unknown format: %s", formatName),
+		})
+		return
+	}
+
+	columns, err := h.store.Columns.Load(r.Context(), h.store.DB, table)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		writeTableColumnsError(w, table, err)
+		return
+	}
+
+	values.Del("limit")
+	values.Del("offset")
+	values.Del("cursor")
+
+	q, err := parseDataQuery(table, columns, values)
+	if err == nil && len(q.Fields) == 0 {
+		err = newRequestError("Table %s has no columns to export", table)
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	rows, err := h.store.Data.Export(r.Context(), h.store.DB, q)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(queryErrorStatus(err))
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Failed to fetch data from table '%s': %v", table, err),
+		})
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", table+"."+format.Extension))
+	w.WriteHeader(http.StatusOK)
+
+	abort := func(err error) {
+		slog.ErrorContext(r.Context(), "export failed", "table", table, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	writer := format.New(w, table)
+	if err := writer.WriteHeader(q.Fields); err != nil {
+		abort(err)
+	}
+
+	for rows.Next() {
+		row, err := rows.Values()
+		if err != nil {
+			abort(err)
+		}
+		if err := writer.WriteRow(row); err != nil {
+			abort(err)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		abort(err)
+	}
+	if err := writer.Close(); err != nil {
+		abort(err)
+	}
+}
+
+// --------------------
+// Export Data End
+// --------------------
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"wolfscream/discord"
+	"wolfscream/notifier"
+	"wolfscream/scheduler"
+	"wolfscream/store"
+
+	"go.opentelemetry.io/otel"
+)
+
+// tracer starts the spans of the steps of a scheduled message run.
+var tracer = otel.Tracer("wolfscream/handlers")
+
+// Handler serves the HTTP API. It reaches the database only through the
+// repositories of store, so tests can give it in-memory fakes.
+type Handler struct {
+	store     *store.Store
+	scheduler *scheduler.Scheduler
+	discord   *discord.Client
+
+	// platforms maps the names in communication_platform to their clients.
+	platforms map[string]notifier.Platform
+
+	eventHooks []EventHook
+}
+
+func New(s *store.Store, sched *scheduler.Scheduler, bot *discord.Client) *Handler {
+	return &Handler{
+		store:     s,
+		scheduler: sched,
+		discord:   bot,
+		platforms: map[string]notifier.Platform{"discord": bot},
+	}
+}
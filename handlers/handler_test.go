@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"wolfscream/datatype"
+	"wolfscream/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeColumns serves the columns of the tables it holds.
+type fakeColumns struct {
+	store.ColumnStore
+	tables map[string][]store.Column
+}
+
+func (f *fakeColumns) Load(ctx context.Context, db store.DBTX, table string) ([]store.Column, error) {
+	columns, ok := f.tables[table]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return columns, nil
+}
+
+// fakeData holds the rows of a single table by id.
+type fakeData struct {
+	store.DataStore
+	rows map[int64]store.Row
+
+	// query is the last query given to Page.
+	query *store.DataQuery
+}
+
+func (f *fakeData) Get(ctx context.Context, db store.DBTX, table string, columns []store.Column, fields []string, id int64) (store.Row, error) {
+	row, ok := f.rows[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	result := store.Row{}
+	for _, field := range fields {
+		result[field] = row[field]
+	}
+	return result, nil
+}
+
+func (f *fakeData) Update(ctx context.Context, db store.DBTX, table string, columns []store.Column, id int64, values map[string]any, replace bool) (store.Row, error) {
+	row, ok := f.rows[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if replace {
+		row = store.Row{idColumn: id}
+	}
+	for name, value := range values {
+		row[name] = value
+	}
+	f.rows[id] = row
+	return row, nil
+}
+
+func (f *fakeData) Delete(ctx context.Context, db store.DBTX, table string, id int64) error {
+	if _, ok := f.rows[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(f.rows, id)
+	return nil
+}
+
+func (f *fakeData) Count(ctx context.Context, db store.DBTX, q *store.DataQuery) (int, error) {
+	return len(f.rows), nil
+}
+
+func (f *fakeData) Page(ctx context.Context, db store.DBTX, q *store.DataQuery) ([]store.Row, *store.DataCursor, error) {
+	f.query = q
+	rows := []store.Row{f.rows[1]}
+	return rows, &store.DataCursor{Sort: q.SortSpec(), Values: []*string{nil}, Tie: "1"}, nil
+}
+
+// fakeDB records the statements run on it. Prepared statements, used by COPY,
+// fail.
+type fakeDB struct {
+	store.Tx
+	queries []string
+}
+
+func (f *fakeDB) Begin(ctx context.Context) (store.Tx, error) {
+	return f, nil
+}
+
+func (f *fakeDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	f.queries = append(f.queries, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (f *fakeDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("unexpected prepared statement: " + query)
+}
+
+func (f *fakeDB) Commit() error {
+	return nil
+}
+
+func (f *fakeDB) Rollback() error {
+	return nil
+}
+
+func newTestHandler() (http.Handler, *fakeData) {
+	data := &fakeData{rows: map[int64]store.Row{
+		1: {"id": int64(1), "name": "disk full", "severity": int64(3)},
+		2: {"id": int64(2), "name": "cpu", "severity": int64(1)},
+	}}
+	h := New(&store.Store{
+		Columns: &fakeColumns{tables: map[string][]store.Column{
+			"alerts": {
+				{Name: "id", Spec: datatype.Spec{Type: "int8"}, Managed: true},
+				{Name: "name", Spec: datatype.Spec{Type: "text"}},
+				{Name: "severity", Spec: datatype.Spec{Type: "int4"}},
+			},
+			"logs": {
+				{Name: "line", Spec: datatype.Spec{Type: "text"}},
+			},
+		}},
+		Data: data,
+	}, nil, nil)
+
+	router := chi.NewRouter()
+	router.Get("/{table-name}/data", h.GetData)
+	router.Get("/{table-name}/data/{id}", h.GetRow)
+	router.Patch("/{table-name}/data/{id}", h.UpdateRow)
+	router.Put("/{table-name}/data/{id}", h.UpdateRow)
+	router.Delete("/{table-name}/data/{id}", h.DeleteData)
+	router.Post("/{table-name}/columns", h.AddColumn)
+	router.Patch("/{table-name}/columns/{column}", h.UpdateColumn)
+	router.Delete("/{table-name}/columns/{column}", h.DeleteColumn)
+	router.Post("/{table-name}/import", h.ImportData)
+	return router, data
+}
+
+type response struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Data    any            `json:"data"`
+	Meta    map[string]any `json:"meta"`
+}
+
+func serve(t *testing.T, handler http.Handler, method string, target string, body string) (int, response) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	var decoded response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s %s: invalid body %q: %v", method, target, recorder.Body.String(), err)
+	}
+	return recorder.Code, decoded
+}
+
+func TestRowRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		status  int
+		message string
+		data    map[string]any
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			target: "/alerts/data/1?fields=name",
+			status: http.StatusOK,
+			data:   map[string]any{"name": "disk full"},
+		},
+		{
+			name:    "get missing row",
+			method:  http.MethodGet,
+			target:  "/alerts/data/9",
+			status:  http.StatusNotFound,
+			message: "Row not found",
+		},
+		{
+			name:    "get missing table",
+			method:  http.MethodGet,
+			target:  "/nope/data/1",
+			status:  http.StatusNotFound,
+			message: "Table nope does not exist",
+		},
+		{
+			name:    "get from a table without id",
+			method:  http.MethodGet,
+			target:  "/logs/data/1",
+			status:  http.StatusBadRequest,
+			message: "Table logs has no id column",
+		},
+		{
+			name:    "get unknown field",
+			method:  http.MethodGet,
+			target:  "/alerts/data/1?fields=nope",
+			status:  http.StatusBadRequest,
+			message: "Unknown column in fields: nope",
+		},
+		{
+			name:   "patch",
+			method: http.MethodPatch,
+			target: "/alerts/data/2",
+			body:   `{"id": 2, "severity": 5}`,
+			status: http.StatusOK,
+			data:   map[string]any{"id": float64(2), "name": "cpu", "severity": float64(5)},
+		},
+		{
+			name:    "patch invalid value",
+			method:  http.MethodPatch,
+			target:  "/alerts/data/2",
+			body:    `{"severity": "high"}`,
+			status:  http.StatusBadRequest,
+			message: `Invalid value for column severity: "high" is not a valid integer`,
+		},
+		{
+			name:    "patch managed column",
+			method:  http.MethodPatch,
+			target:  "/alerts/data/2",
+			body:    `{"id": 3}`,
+			status:  http.StatusBadRequest,
+			message: "Column id is managed and can't be written",
+		},
+		{
+			name:   "put resets missing columns",
+			method: http.MethodPut,
+			target: "/alerts/data/2",
+			body:   `{"name": "memory"}`,
+			status: http.StatusOK,
+			data:   map[string]any{"id": float64(2), "name": "memory"},
+		},
+		{
+			name:    "patch missing row",
+			method:  http.MethodPatch,
+			target:  "/alerts/data/9",
+			body:    `{"name": "x"}`,
+			status:  http.StatusNotFound,
+			message: "Row not found",
+		},
+		{
+			name:    "delete",
+			method:  http.MethodDelete,
+			target:  "/alerts/data/1",
+			status:  http.StatusOK,
+			message: "Data deleted successfully",
+		},
+		{
+			name:    "delete missing row",
+			method:  http.MethodDelete,
+			target:  "/alerts/data/9",
+			status:  http.StatusNotFound,
+			message: "Row not found",
+		},
+		{
+			name:    "delete invalid id",
+			method:  http.MethodDelete,
+			target:  "/alerts/data/x",
+			status:  http.StatusBadRequest,
+			message: "Invalid id: x",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, _ := newTestHandler()
+
+			status, body := serve(t, handler, test.method, test.target, test.body)
+			if status != test.status {
+				t.Fatalf("status = %d, want %d: %+v", status, test.status, body)
+			}
+			if test.message != "" && body.Message != test.message {
+				t.Errorf("message = %q, want %q", body.Message, test.message)
+			}
+			if test.data != nil && !reflect.DeepEqual(body.Data, test.data) {
+				t.Errorf("data = %v, want %v", body.Data, test.data)
+			}
+		})
+	}
+}
+
+func TestGetDataPagination(t *testing.T) {
+	handler, data := newTestHandler()
+
+	status, body := serve(t, handler, http.MethodGet, "/alerts/data?sort=-severity&limit=1&filter=severity%20!%3D%200", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %+v", status, body)
+	}
+
+	q := data.query
+	if q.Limit != 1 || q.SortSpec() != "-severity" || len(q.Conditions) != 1 {
+		t.Errorf("query = %+v", q)
+	}
+	if rows, ok := body.Data.([]any); !ok || len(rows) != 1 {
+		t.Errorf("data = %v, want a page of 1 row", body.Data)
+	}
+	if body.Meta["total"] != float64(2) || body.Meta["offset"] != float64(0) {
+		t.Errorf("meta = %v", body.Meta)
+	}
+
+	cursor, ok := body.Meta["next_cursor"].(string)
+	if !ok {
+		t.Fatalf("next_cursor = %v, want a cursor", body.Meta["next_cursor"])
+	}
+
+	status, body = serve(t, handler, http.MethodGet, "/alerts/data?sort=-severity&limit=1&cursor="+cursor, "")
+	if status != http.StatusOK {
+		t.Fatalf("status with cursor = %d: %+v", status, body)
+	}
+	if data.query.Cursor == nil || data.query.Cursor.Tie != "1" {
+		t.Errorf("cursor = %+v", data.query.Cursor)
+	}
+	if _, ok := body.Meta["offset"]; ok {
+		t.Error("offset is reported with a cursor")
+	}
+
+	status, body = serve(t, handler, http.MethodGet, "/alerts/data?sort=name&cursor="+cursor, "")
+	if status != http.StatusBadRequest || body.Message != "Invalid cursor" {
+		t.Errorf("cursor of another sort: status = %d, message = %q", status, body.Message)
+	}
+}
+
+func TestReservedColumnName(t *testing.T) {
+	handler, _ := newTestHandler()
+
+	for _, request := range []struct{ method, target, body string }{
+		{http.MethodPost, "/alerts/columns", `{"name": "id", "type": "text"}`},
+		{http.MethodPatch, "/alerts/columns/name", `{"name": "id"}`},
+	} {
+		status, body := serve(t, handler, request.method, request.target, request.body)
+		if status != http.StatusBadRequest || body.Message != "Column name id is reserved" {
+			t.Errorf("%s %s: status = %d, message = %q", request.method, request.target, status, body.Message)
+		}
+	}
+}
+
+func TestDeleteColumn(t *testing.T) {
+	handler, _ := newTestHandler()
+
+	tests := []struct {
+		target  string
+		status  int
+		message string
+	}{
+		{"/alerts/columns/id", http.StatusBadRequest, "Column id is reserved and can't be deleted"},
+		{"/alerts/columns/nope", http.StatusNotFound, "Column not found"},
+		{"/nope/columns/name", http.StatusNotFound, "Table nope does not exist"},
+	}
+
+	for _, test := range tests {
+		status, body := serve(t, handler, http.MethodDelete, test.target, "")
+		if status != test.status || body.Message != test.message {
+			t.Errorf("DELETE %s: status = %d, message = %q", test.target, status, body.Message)
+		}
+	}
+}
+
+func TestImportDefaultRowsInAtomicMode(t *testing.T) {
+	db := &fakeDB{}
+	h := New(&store.Store{
+		DB: db,
+		Columns: &fakeColumns{tables: map[string][]store.Column{
+			"alerts": {
+				{Name: "id", Spec: datatype.Spec{Type: "int8"}, Managed: true},
+				{Name: "severity", Spec: datatype.Spec{Type: "int4"}},
+			},
+		}},
+		Data: store.New(nil).Data,
+	}, nil, nil)
+	router := chi.NewRouter()
+	router.Post("/{table-name}/import", h.ImportData)
+
+	for _, request := range []struct{ format, body string }{
+		{"ndjson", "{}\n{}\n"},
+		{"csv", "severity\n\"\"\n\"\"\n"},
+	} {
+		db.queries = nil
+
+		status, body := serve(t, router, http.MethodPost, "/alerts/import?mode=atomic&format="+request.format, request.body)
+		if status != http.StatusOK {
+			t.Fatalf("%s: status = %d: %+v", request.format, status, body)
+		}
+		inserts := 0
+		for _, query := range db.queries {
+			if query == `INSERT INTO "alerts" DEFAULT VALUES` {
+				inserts++
+			}
+		}
+		if inserts != 2 {
+			t.Errorf("%s: queries = %q, want 2 inserts of the defaults", request.format, db.queries)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"wolfscream/importer"
+	"wolfscream/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return "csv"
+	case strings.HasPrefix(contentType, "application/x-ndjson"), strings.HasPrefix(contentType, "application/jsonl"):
+		return "ndjson"
+	default:
+		return "json"
+	}
+}
+
+// --------------------
+// Import Data
+// --------------------
+
+// ImportData imports a CSV, JSON array or NDJSON body into a table.
+//
+// Query parameters:
+//
+//	format   csv, json, ndjson or jsonl, defaults to the Content-Type
+//	mode     atomic (default) imports nothing if a row is invalid, skip
+//	         imports the valid rows and reports the others
+//	mapping  CSV header to column renames, "src:column,other:" where an
+//	         empty column ignores the field
+//	columns  columns of JSON records, defaults to the keys of the first one
+//
+// Rows are validated against the column metadata before they are sent to
+// Postgres. A missing JSON key or an empty CSV field of a column that isn't a
+// string gets the column default. Atomic imports stream the rows with COPY,
+// a constraint violation fails the import. Skip imports insert every row in
+// its own savepoint so constraint violations are reported per row.
+func (h *Handler) ImportData(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	table := chi.URLParam(r, "table-name")
+
+	columns, err := h.store.Columns.Load(r.Context(), h.store.DB, table)
+	if err != nil {
+		writeTableColumnsError(w, table, err)
+		return
+	}
+
+	mode := importer.Mode(r.URL.Query().Get("mode"))
+	if mode == "" {
+		mode = importer.Atomic
+	}
+
+	var reader importer.Reader
+	switch format := importFormat(r); {
+	case mode != importer.Atomic && mode != importer.Skip:
+		err = newRequestError("Unknown mode: %s", mode)
+	case format == "csv":
+		var mapping map[string]string
+		mapping, err = importer.ParseMapping(r.URL.Query().Get("mapping"))
+		if err == nil {
+			reader, err = importer.NewCSV(r.Body, mapping)
+		}
+	case format == "json", format == "ndjson", format == "jsonl":
+		var jsonColumns []string
+		if value := r.URL.Query().Get("columns"); value != "" {
+			for column := range strings.SplitSeq(value, ",") {
+				jsonColumns = append(jsonColumns, strings.TrimSpace(column))
+			}
+		}
+		reader, err = importer.NewJSON(r.Body, jsonColumns)
+	default:
+		err = newRequestError("Unknown format: %s", format)
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tx, err := h.store.DB.Begin(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Failed to start transaction",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var writer store.RowWriter
+	if mode == importer.Skip {
+		writer = h.store.Data.Inserter(tx, table)
+	} else {
+		writer = h.store.Data.Copier(tx, table)
+	}
+
+	result, err := importer.Run(r.Context(), reader, columns, mode, writer)
+	if err != nil {
+		var inputErr *importer.InputError
+		status := http.StatusBadRequest
+		if !errors.As(err, &inputErr) {
+			status = queryErrorStatus(err)
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":  "error",
+			"message": fmt.Sprintf("Import failed: %v", err),
+			"data": map[string]any{
+				"mode":     mode,
+				"inserted": 0,
+				"failed":   result.Failed,
+				"errors":   result.Errors,
+			},
+		})
+		return
+	}
+
+	if result.Failed > 0 && mode == importer.Atomic {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":  "error",
+			"message": fmt.Sprintf("%d rows are invalid, nothing was imported", result.Failed),
+			"data": map[string]any{
+				"mode":     mode,
+				"inserted": 0,
+				"failed":   result.Failed,
+				"errors":   result.Errors,
+			},
+		})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Failed to commit transaction",
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  "success",
+		"message": fmt.Sprintf("%d rows imported", result.Inserted),
+		"data": map[string]any{
+			"mode":     mode,
+			"inserted": result.Inserted,
+			"failed":   result.Failed,
+			"errors":   result.Errors,
+		},
+	})
+}
+
+// --------------------
+// Import Data End
+// --------------------
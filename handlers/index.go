@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"wolfscream/datatype"
+	"wolfscream/store"
+	"wolfscream/validator"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	indexMethodBtree = "btree"
+	indexMethodGin   = "gin"
+)
+
+// --------------------
+// Create Index
+// --------------------
+
+// CreateIndex builds a btree or gin index with CREATE INDEX CONCURRENTLY so
+// the table stays writable during the build. A failed build leaves an invalid
+// index behind, it is dropped before answering.
+func (h *Handler) CreateIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tableName := chi.URLParam(r, "table-name")
+
+	type CreateIndexBody struct {
+		Name    string   `json:"name" validate:"omitempty,snakecase,max=63"`
+		Columns []string `json:"columns" validate:"required,min=1,dive,required"`
+		Method  string   `json:"method" validate:"omitempty,oneof=btree gin"`
+		Unique  bool     `json:"unique"`
+	}
+
+	var body CreateIndexBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Invalid request body",
+		})
+		return
+	}
+
+	if err := validator.Validate.Struct(body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status": "error",
+			"errors": validator.FormatError(err),
+		})
+		return
+	}
+
+	columns, err := h.store.Columns.Load(r.Context(), h.store.DB, tableName)
+	if err != nil {
+		writeTableColumnsError(w, tableName, err)
+		return
+	}
+
+	if body.Method == "" {
+		body.Method = indexMethodBtree
+	}
+
+	byName := map[string]store.Column{}
+	for _, column := range columns {
+		byName[column.Name] = column
+	}
+
+	for _, name := range body.Columns {
+		column, ok := byName[name]
+		switch {
+		case !ok:
+			err = newRequestError("Unknown column: %s", name)
+		case body.Method == indexMethodGin && column.Type != "jsonb" && column.Type != "text[]":
+			err = newRequestError("gin indexes only apply to jsonb and text[] columns, %s is %s", name, column.Type)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == nil && body.Unique && body.Method != indexMethodBtree {
+		err = newRequestError("Only btree indexes can be unique")
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if body.Name == "" {
+		body.Name = datatype.Identifier("idx", append([]string{tableName}, body.Columns...)...)
+	}
+
+	index := store.Index{
+		Name:    body.Name,
+		Method:  body.Method,
+		Unique:  body.Unique,
+		Columns: body.Columns,
+	}
+
+	if err := h.store.Indexes.Create(r.Context(), h.store.DB, tableName, &index); err != nil {
+		w.WriteHeader(queryErrorStatus(err))
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  "success",
+		"message": "Index created successfully",
+		"data":    index,
+	})
+}
+
+// --------------------
+// Create Index End
+// --------------------
+
+// --------------------
+// List Indexes
+// --------------------
+func (h *Handler) ListIndexes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tableName := chi.URLParam(r, "table-name")
+
+	indexes, err := h.store.Indexes.List(r.Context(), h.store.DB, tableName)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": "success",
+		"data":   indexes,
+	})
+}
+
+// --------------------
+// List Indexes End
+// --------------------
+
+// --------------------
+// Drop Index
+// --------------------
+func (h *Handler) DropIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tableName := chi.URLParam(r, "table-name")
+	indexName := chi.URLParam(r, "index")
+
+	if _, err := h.store.Indexes.Get(r.Context(), h.store.DB, tableName, indexName); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "error",
+				"message": "Index not found",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Failed to query index: %v", err),
+		})
+		return
+	}
+
+	if err := h.store.Indexes.Drop(r.Context(), h.store.DB, indexName); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Index dropped successfully",
+	})
+}
+
+// --------------------
+// Drop Index End
+// --------------------
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"wolfscream/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultLogLimit = 100
+	maxLogLimit     = 1000
+)
+
+// parseLogQuery reads the query string accepted by the log API into a filter
+// of the logs of scheduledMessageId:
+//
+//	level   comma separated levels, e.g. "WARN,ERROR"
+//	run_id  logs of a single run
+//	since   RFC 3339 time, inclusive
+//	until   RFC 3339 time, exclusive
+//	search  substring of the text, ignoring case
+//	limit   page size
+//	cursor  next_cursor of the previous page
+func parseLogQuery(scheduledMessageId int, values url.Values) (*store.LogFilter, error) {
+	filter := &store.LogFilter{
+		ScheduledMessageId: scheduledMessageId,
+		RunId:              values.Get("run_id"),
+		Search:             values.Get("search"),
+		Limit:              defaultLogLimit,
+	}
+
+	if levels := values.Get("level"); levels != "" {
+		for level := range strings.SplitSeq(levels, ",") {
+			level = strings.ToUpper(strings.TrimSpace(level))
+			if !slices.Contains(store.LogLevels, level) {
+				return nil, newRequestError("level must be one of %s", strings.Join(store.LogLevels, ", "))
+			}
+			filter.Levels = append(filter.Levels, level)
+		}
+	}
+
+	if filter.RunId != "" {
+		if _, err := uuid.Parse(filter.RunId); err != nil {
+			return nil, newRequestError("run_id must be a UUID")
+		}
+	}
+
+	for name, field := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := values.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, newRequestError("%s must be an RFC 3339 time", name)
+		}
+		*field = &t
+	}
+	if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+		return nil, newRequestError("since must be before until")
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		var err error
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxLogLimit {
+			return nil, newRequestError("limit must be between 1 and %d", maxLogLimit)
+		}
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		after, err := decodeLogCursor(cursor)
+		if err != nil {
+			return nil, newRequestError("Invalid cursor")
+		}
+		filter.After = after
+	}
+
+	return filter, nil
+}
+
+func encodeLogCursor(cursor store.LogCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeLogCursor(value string) (*store.LogCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor store.LogCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.CreatedAt == "" || cursor.Id == "" {
+		return nil, errors.New("incomplete cursor")
+	}
+	return &cursor, nil
+}
@@ -2,60 +2,73 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"wolfscream/database"
+	"strconv"
+
+	"wolfscream/notifier"
 )
 
+// writePlatformError answers a request that needs a platform the server
+// can't reach. The rest of the API keeps working, so it is a 503 rather
+// than a 500.
+func writePlatformError(w http.ResponseWriter, name string, err error) {
+	message := fmt.Sprintf("%s is unavailable: %v", name, err)
+	if errors.Is(err, notifier.ErrNotConfigured) {
+		message = fmt.Sprintf("%s is not configured", name)
+	}
+
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "error",
+		"message": message,
+	})
+}
+
 // --------------------
 // List Platforms
 // --------------------
-func ListPlatforms(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 
-	query := "SELECT id, name, image_url FROM communication_platform;"
+// ListPlatforms lists the platforms with the connection status of their
+// client. Platforms connect on first use, so idle is not an error.
+func (h *Handler) ListPlatforms(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	rows, err := database.DB.Query(query)
+	platforms, err := h.store.Platforms.List(r.Context(), h.store.DB)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to query columns: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer rows.Close()
 
 	type CommunicationPlatform struct {
-		Id       string `json:"id"`
-		Name     string `json:"name"`
-		ImageUrl string `json:"image_url"`
+		Id       string          `json:"id"`
+		Name     string          `json:"name"`
+		ImageUrl string          `json:"image_url"`
+		Status   notifier.Status `json:"status"`
 	}
 
 	communicationPlatforms := []CommunicationPlatform{}
 
-	for rows.Next() {
-		var communicationPlatform CommunicationPlatform
-		if err := rows.Scan(&communicationPlatform.Id, &communicationPlatform.Name, &communicationPlatform.ImageUrl); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": fmt.Sprintf("Failed to scan column: %v", err),
-			})
-			return
+	for _, platform := range platforms {
+		communicationPlatform := CommunicationPlatform{
+			Id:   strconv.Itoa(platform.Id),
+			Name: platform.Name,
+		}
+		if platform.ImageUrl != nil {
+			communicationPlatform.ImageUrl = *platform.ImageUrl
+		}
+		communicationPlatform.Status = notifier.Status{State: notifier.StateUnsupported}
+		if client, ok := h.platforms[platform.Name]; ok {
+			communicationPlatform.Status = client.Status()
 		}
 		communicationPlatforms = append(communicationPlatforms, communicationPlatform)
 	}
 
-	if err := rows.Err(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to read rows: %v", err),
-		})
-		return
-	}
-
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]any{
 		"status": "success",
@@ -4,26 +4,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"wolfscream/database"
 	"wolfscream/validator"
 )
 
 // --------------------
 // List Rules
 // --------------------
-func ListRules(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListRules(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	rows, err := database.DB.Query("SELECT id, name, description, rule FROM scheduled_message_rule ORDER BY created_at ASC;")
+	rules, err := h.store.Rules.List(r.Context(), h.store.DB)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to query rules: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer rows.Close()
 
 	type ScheduledMessageRule struct {
 		Id          int     `json:"id"`
@@ -33,27 +31,13 @@ func ListRules(w http.ResponseWriter, r *http.Request) {
 	}
 
 	scheduledMessageRules := []ScheduledMessageRule{}
-
-	for rows.Next() {
-		var scheduledMessageRule ScheduledMessageRule
-		if err := rows.Scan(&scheduledMessageRule.Id, &scheduledMessageRule.Name, &scheduledMessageRule.Description, &scheduledMessageRule.Rule); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": fmt.Sprintf("Failed to scan rule: %v", err),
-			})
-			return
-		}
-		scheduledMessageRules = append(scheduledMessageRules, scheduledMessageRule)
-	}
-
-	if err := rows.Err(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to read rows: %v", err),
+	for _, rule := range rules {
+		scheduledMessageRules = append(scheduledMessageRules, ScheduledMessageRule{
+			Id:          rule.Id,
+			Name:        rule.Name,
+			Description: rule.Description,
+			Rule:        rule.Text,
 		})
-		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -70,7 +54,7 @@ func ListRules(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // Add Rule
 // --------------------
-func AddRule(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AddRule(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	type AddRuleBody struct {
@@ -99,8 +83,7 @@ func AddRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := "INSERT INTO scheduled_message_rule(name, rule, description) VALUES ($1, $2, $3);"
-	if _, err := database.DB.Exec(query, body.Name, body.Rule, body.Description); err != nil {
+	if err := h.store.Rules.Create(r.Context(), h.store.DB, body.Name, body.Rule, body.Description); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
@@ -1,58 +1,48 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	"wolfscream/database"
-	"wolfscream/discord"
+	"wolfscream/logging"
 	"wolfscream/models"
-	"wolfscream/scheduler"
+	"wolfscream/notifier"
+	"wolfscream/rule"
+	"wolfscream/store"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func UpdateScheduledMessages(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateScheduledMessages(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 }
 
 // --------------------
 // List Scheduled Messages
 // --------------------
-func ListScheduledMessages(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListScheduledMessages(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	query := `
-    SELECT 
-		sm.id,
-      	sm.name,
-		sm.description,
-		sm.schedule_type,
-		t.name,
-		p.name,
-		p.image_url,
-      	rsm.id
-    FROM scheduled_messages sm
-		JOIN platforms p ON sm.platform_id = p.id
-		JOIN tables t on sm.table_id = t.id
-    LEFT JOIN running_scheduled_messages rsm ON sm.id = rsm.scheduled_message_id
-    ORDER BY sm.created_at DESC;`
-
-	rows, err := database.DB.Query(query)
+	scheduledMessages, err := h.store.ScheduledMessages.List(r.Context(), h.store.DB)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to query columns: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer rows.Close()
 
 	type ScheduledMessage struct {
 		Id           int    `json:"id"`
@@ -83,26 +73,13 @@ func ListScheduledMessages(w http.ResponseWriter, r *http.Request) {
 
 	var data []Data
 
-	for rows.Next() {
-		var d Data
-		if err := rows.Scan(&d.Id, &d.Name, &d.Description, &d.ScheduleType, &d.Table.Name, &d.Platform.Name, &d.Platform.ImageUrl, &d.RunningScheduledMessage.Id); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": fmt.Sprintf("Failed to scan column: %v", err),
-			})
-			return
-		}
-		data = append(data, d)
-	}
-
-	if err := rows.Err(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to read rows: %v", err),
+	for _, sm := range scheduledMessages {
+		data = append(data, Data{
+			ScheduledMessage:        ScheduledMessage{Id: sm.Id, Name: sm.Name, Description: sm.Description, ScheduleType: sm.ScheduleType},
+			Table:                   Table{Name: sm.Table},
+			Platform:                Platform{Name: sm.Platform, ImageUrl: sm.PlatformImageUrl},
+			RunningScheduledMessage: RunningScheduledMessage{Id: sm.RunningId},
 		})
-		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -119,7 +96,7 @@ func ListScheduledMessages(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // GetScheduledMessage
 // --------------------
-func GetScheduledMessage(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetScheduledMessage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	scheduledMessageName := chi.URLParam(r, "scheduled-message-name")
@@ -159,55 +136,9 @@ func GetScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		ExecutionHistory        ExecutionStatistic      `json:"execution_statistics"`
 	}
 
-	var data Data
-
-	query := `
-		SELECT
-			sm.id, 
-			sm.name,
-			sm.description,
-			sm.schedule_type,
-			t.name AS table_name,
-			p.name AS platform_name,
-			p.image_url,
-			rsm.id AS running_id,
-			smeh.success_count,
-			smeh.failed_count
-		FROM scheduled_messages sm
-		JOIN platforms p 
-			ON sm.platform_id = p.id
-		JOIN tables t 
-			ON sm.table_id = t.id
-		LEFT JOIN running_scheduled_messages rsm 
-			ON sm.id = rsm.scheduled_message_id
-
-		LEFT JOIN LATERAL (
-			SELECT
-				COUNT(*) FILTER (WHERE status = 'success') AS success_count,
-				COUNT(*) FILTER (WHERE status = 'failed')  AS failed_count
-			FROM scheduled_message_execution_history
-			WHERE scheduled_message_id = sm.id
-		) smeh ON true
-		WHERE sm.name = $1;
-	`
-
-	err := database.DB.
-		QueryRow(query, scheduledMessageName).
-		Scan(
-			&data.Id,
-			&data.Name,
-			&data.Description,
-			&data.ScheduleType,
-			&data.Table.Name,
-			&data.Platform.Name,
-			&data.Platform.ImageUrl,
-			&data.RunningScheduledMessage.Id,
-			&data.ExecutionHistory.SuccessCount,
-			&data.ExecutionHistory.FailedCount,
-		)
-
+	sm, err := h.store.ScheduledMessages.Get(r.Context(), h.store.DB, scheduledMessageName)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == store.ErrNotFound {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]string{
 				"status":  "error",
@@ -224,18 +155,16 @@ func GetScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if data.RunningScheduledMessage.Id != nil {
-		for _, entry := range scheduler.Cron.Entries() {
-			if entry.ID == cron.EntryID(*data.RunningScheduledMessage.Id) {
-
-				if !entry.Prev.IsZero() {
-					data.RunningScheduledMessage.PrevRun = &entry.Prev
-				}
+	data := Data{
+		ScheduledMessage:        ScheduledMessage{Id: sm.Id, Name: sm.Name, Description: sm.Description, ScheduleType: sm.ScheduleType},
+		Table:                   Table{Name: sm.Table},
+		Platform:                Platform{Name: sm.Platform, ImageUrl: sm.PlatformImageUrl},
+		RunningScheduledMessage: RunningScheduledMessage{Id: sm.RunningId},
+		ExecutionHistory:        ExecutionStatistic{SuccessCount: sm.SuccessCount, FailedCount: sm.FailedCount},
+	}
 
-				data.RunningScheduledMessage.NextRun = &entry.Next
-				break
-			}
-		}
+	if data.RunningScheduledMessage.Id != nil {
+		data.RunningScheduledMessage.PrevRun, data.RunningScheduledMessage.NextRun = h.scheduler.Runs(*data.RunningScheduledMessage.Id)
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -269,6 +198,21 @@ type Cron struct {
 	DayOfWeek  int `json:"day_of_week"`
 }
 
+// schedule returns the fields as stored in scheduled_message_cron.
+func (c Cron) schedule() store.Cron {
+	field := func(value int) *string {
+		text := strconv.Itoa(value)
+		return &text
+	}
+	return store.Cron{
+		Minute:     field(c.Minute),
+		Hour:       field(c.Hour),
+		DayOfMonth: field(c.DayOfMonth),
+		Month:      field(c.Month),
+		DayOfWeek:  field(c.DayOfWeek),
+	}
+}
+
 type AddScheduleMessageBody struct {
 	Name         string  `json:"name"`
 	Message      string  `json:"message"`
@@ -285,7 +229,7 @@ type AddScheduleMessageBody struct {
 	Cron Cron `json:"cron"`
 }
 
-func AddScheduledMessage(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AddScheduledMessage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var body AddScheduleMessageBody
@@ -298,7 +242,9 @@ func AddScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := database.DB.Begin()
+	ctx := r.Context()
+
+	tx, err := h.store.DB.Begin(ctx)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -309,28 +255,15 @@ func AddScheduledMessage(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	var scheduledMessageID int
-
-	query := `
-		INSERT INTO scheduled_messages (
-			name, description, table_id, message, rule, platform_id, schedule_type
-		)
-		VALUES (
-			$1, $2, $3, $4, $5, $6, $7
-		)
-		RETURNING
-			id;
-	`
-	err = tx.QueryRow(
-		query,
-		body.Name,
-		body.Description,
-		body.TableId,
-		body.Message,
-		body.Rule,
-		body.PlatformId,
-		body.ScheduleType,
-	).Scan(&scheduledMessageID)
+	scheduledMessageID, err := h.store.ScheduledMessages.Create(ctx, tx, models.ScheduledMessage{
+		Name:         body.Name,
+		Description:  body.Description,
+		TableId:      body.TableId,
+		Message:      body.Message,
+		Rule:         body.Rule,
+		PlatformId:   body.PlatformId,
+		ScheduleType: body.ScheduleType,
+	})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -340,8 +273,7 @@ func AddScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var platformName string
-	err = tx.QueryRow("SELECT name FROM platforms WHERE id = $1", body.PlatformId).Scan(&platformName)
+	platform, err := h.store.Platforms.Get(ctx, tx, body.PlatformId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -351,10 +283,9 @@ func AddScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switch platformName {
+	switch platform.Name {
 	case "discord":
-		query := "INSERT INTO discord_configs(channel_id, scheduled_message_id) VALUES ($1, $2)"
-		if _, err := tx.Exec(query, body.DiscordConfig.ChannelId, scheduledMessageID); err != nil {
+		if err := h.store.ScheduledMessages.SetDiscordConfig(ctx, tx, scheduledMessageID, body.DiscordConfig.ChannelId); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{
 				"status":  "error",
@@ -366,15 +297,15 @@ func AddScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Unsupported platform: %s", platformName),
+			"message": fmt.Sprintf("Unsupported platform: %s", platform.Name),
 		})
 		return
 	}
 
 	switch body.ScheduleType {
 	case "interval":
-		query = "INSERT INTO intervals(value, unit, scheduled_message_id) VALUES ($1, $2, $3);"
-		if _, err := tx.Exec(query, body.Interval.Value, body.Interval.Unit, scheduledMessageID); err != nil {
+		interval := store.Interval{Value: body.Interval.Value, Unit: body.Interval.Unit}
+		if err := h.store.ScheduledMessages.SetInterval(ctx, tx, scheduledMessageID, interval); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{
 				"status":  "error",
@@ -383,8 +314,7 @@ func AddScheduledMessage(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	case "cron":
-		query = "INSERT INTO cron_jobs(minute, hour, day_of_month, month, day_of_week, scheduled_message_id) VALUES ($1, $2, $3, $4, $5, $6);"
-		if _, err := tx.Exec(query, body.Cron.Minute, body.Cron.Hour, body.Cron.DayOfMonth, body.Cron.Month, body.Cron.DayOfWeek, scheduledMessageID); err != nil {
+		if err := h.store.ScheduledMessages.SetCron(ctx, tx, scheduledMessageID, body.Cron.schedule()); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{
 				"status":  "error",
@@ -419,54 +349,15 @@ func AddScheduledMessage(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // Add Scheduled Message End
 // --------------------
-func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	ctx := r.Context()
 	scheduledMessageName := chi.URLParam(r, "scheduled-message-name")
 
-	type ScheduledMessage struct {
-		Id           int
-		Rule         string
-		Message      string
-		ScheduleType string
-	}
-
-	type Table struct {
-		name string
-	}
-
-	type Platform struct {
-		name string
-	}
-
-	var (
-		scheduledMessage ScheduledMessage
-		table            Table
-		platform         Platform
-	)
-
-	err := database.DB.QueryRow(`
-	SELECT 
-		sm.id,
-		sm.schedule_type,
-		sm.rule,
-		sm.message,
-		t.name,
-		p.name
-		FROM scheduled_messages sm
-		JOIN tables t ON sm.table_id = t.id
-		JOIN platforms p ON sm.platform_id = p.id
-		WHERE sm.name = $1
-	`, scheduledMessageName).Scan(
-		&scheduledMessage.Id,
-		&scheduledMessage.ScheduleType,
-		&scheduledMessage.Rule,
-		&scheduledMessage.Message,
-		&table.name,
-		&platform.name,
-	)
+	scheduledMessage, err := h.store.ScheduledMessages.Definition(ctx, h.store.DB, scheduledMessageName)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == store.ErrNotFound {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]string{
 				"status":  "error",
@@ -482,17 +373,11 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type DiscordConfig struct {
-		ChannelId string
-	}
 	var platformConfig any
 
-	switch platform.name {
+	switch scheduledMessage.Platform {
 	case "discord":
-		var discordConfig DiscordConfig
-		err = database.DB.QueryRow(`
-				SELECT channel_id FROM discord_configs WHERE scheduled_message_id = $1
-			`, scheduledMessage.Id).Scan(&discordConfig.ChannelId)
+		discordConfig, err := h.store.ScheduledMessages.DiscordConfig(ctx, h.store.DB, scheduledMessage.Id)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{
@@ -501,7 +386,7 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
-		platformConfig = discordConfig
+		platformConfig = *discordConfig
 	default:
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -511,27 +396,10 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type Interval struct {
-		Value int
-		Unit  string
-	}
-
-	type CronJob struct {
-		Minute     *string
-		Hour       *string
-		DayOfMonth *string
-		Month      *string
-		DayOfWeek  *string
-	}
-
 	var cronSpec string
 	switch scheduledMessage.ScheduleType {
 	case "interval":
-		var interval Interval
-		err = database.DB.QueryRow(`
-				SELECT value, unit FROM intervals
-				WHERE scheduled_message_id = $1
-			`, scheduledMessage.Id).Scan(&interval.Value, &interval.Unit)
+		interval, err := h.store.ScheduledMessages.Interval(ctx, h.store.DB, scheduledMessage.Id)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{
@@ -541,11 +409,8 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		cronSpec = fmt.Sprintf("@every %d%s", interval.Value, interval.Unit)
-	case "cronjob":
-		var cronJob CronJob
-		err = database.DB.QueryRow(`
-				SELECT minute, hour, day_of_month, month, day_of_week FROM cronjobs WHERE scheduled_message_id = $1
-			`, scheduledMessage.Id).Scan(&cronJob.Minute, &cronJob.Hour, &cronJob.DayOfMonth, &cronJob.Month, &cronJob.DayOfWeek)
+	case "cron":
+		cronJob, err := h.store.ScheduledMessages.Cron(ctx, h.store.DB, scheduledMessage.Id)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{
@@ -558,7 +423,7 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 			if p == nil {
 				return "*"
 			}
-			return fmt.Sprintf("%s", *p)
+			return *p
 		}
 		cronSpec = fmt.Sprintf("%s %s %s %s %s",
 			opt(cronJob.Minute),
@@ -576,77 +441,83 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sendMessage := func() {
-		rows, err := database.DB.Query(fmt.Sprintf("SELECT * FROM %s", table.name))
-		if err != nil {
-			logText := fmt.Sprintf("Failed to query table: %v", err)
-			database.DB.Exec("INSERT INTO scheduled_message_error_logs (scheduled_message_id, text, level) VALUES ($1, $2, $3);", scheduledMessage.Id, logText, "ERROR")
-			return
-		}
-		defer rows.Close()
+	conditions, err := rule.Parse(scheduledMessage.Rule)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Invalid rule: %v", err),
+		})
+		return
+	}
 
-		columns, _ := rows.Columns()
-		messages := []string{}
+	var entryId atomic.Int64
 
-		for rows.Next() {
-			values := make([]any, len(columns))
-			pointers := make([]any, len(columns))
-			for i := range values {
-				pointers[i] = &values[i]
-			}
+	sendMessage := func(ctx context.Context) {
+		// the outcome of a canceled run is still recorded
+		record := context.WithoutCancel(ctx)
 
-			if err := rows.Scan(pointers...); err != nil {
-				continue
-			}
+		runId := logging.RunID(ctx)
+		h.publishScheduledMessageEvent(scheduledMessageName, models.ScheduledMessageEvent{Event: "run_started", RunId: runId})
 
-			rowMap := make(map[string]any)
-			for i, column := range columns {
-				rowMap[column] = values[i]
+		rowsScanned, rowsMatched := 0, 0
+		status := "skipped"
+		defer func() {
+			span := trace.SpanFromContext(ctx)
+			span.SetAttributes(
+				attribute.String("scheduled_message.status", status),
+				attribute.Int("scheduled_message.rows_scanned", rowsScanned),
+				attribute.Int("scheduled_message.rows_matched", rowsMatched),
+			)
+			if status == "failed" {
+				span.SetStatus(codes.Error, "run failed")
 			}
 
-			match := true
-			for rule := range strings.SplitSeq(scheduledMessage.Rule, ";") {
-				rule = strings.TrimSpace(rule)
-				if rule == "" {
-					continue
-				}
-
-				parts := strings.SplitN(rule, " ", 3)
-				if len(parts) != 3 {
-					match = false
-					break
-				}
-
-				key, operator, val := parts[0], parts[1], parts[2]
-
-				v, ok := rowMap[key]
-				if !ok {
-					match = false
-					break
-				}
-
-				switch operator {
-				case "==":
-					if fmt.Sprintf("%v", v) != val {
-						match = false
-					}
-				case "!=":
-					if fmt.Sprintf("%v", v) == val {
-						match = false
-					}
-				default:
-					match = false
-				}
+			slog.InfoContext(ctx, "scheduled message run finished",
+				"scheduled_message", scheduledMessageName,
+				"status", status,
+				"rows_scanned", rowsScanned,
+				"rows_matched", rowsMatched,
+			)
+			h.publishScheduledMessageEvent(scheduledMessageName, models.ScheduledMessageEvent{
+				Event:       "run_finished",
+				Status:      status,
+				RunId:       runId,
+				RowsScanned: &rowsScanned,
+				RowsMatched: &rowsMatched,
+			})
 
-				if !match {
-					break
-				}
+			prevRun, nextRun := h.scheduler.Runs(int(entryId.Load()))
+			h.publishScheduledMessageEvent(scheduledMessageName, models.ScheduledMessageEvent{
+				Event:   "next_run",
+				PrevRun: prevRun,
+				NextRun: nextRun,
+			})
+		}()
+
+		scanCtx, scan := tracer.Start(ctx, "scheduled_message.scan", trace.WithAttributes(
+			attribute.String("scheduled_message.table", scheduledMessage.Table),
+		))
+		matched := []map[string]any{}
+		err := h.store.Data.Scan(scanCtx, h.store.DB, scheduledMessage.Table, func(row map[string]any) {
+			rowsScanned++
+			if rule.Match(conditions, row) {
+				rowsMatched++
+				matched = append(matched, row)
 			}
+		})
+		scan.End()
 
-			if !match {
-				continue
-			}
+		// a canceled query ends the rows early, don't send a partial result
+		if err != nil {
+			status = "failed"
+			h.writeScheduledMessageLog(record, scheduledMessage.Id, scheduledMessageName, "ERROR", fmt.Sprintf("Failed to scan table: %v", err))
+			return
+		}
 
+		_, render := tracer.Start(ctx, "scheduled_message.render")
+		messages := []string{}
+		for _, rowMap := range matched {
 			message := scheduledMessage.Message
 			for col, val := range rowMap {
 				message = strings.ReplaceAll(message, "{{"+col+"}}", fmt.Sprintf("%v", val))
@@ -654,30 +525,41 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 
 			messages = append(messages, message)
 		}
+		render.End()
 
 		if len(messages) == 0 {
 			return
 		}
 
-		switch platform.name {
+		switch scheduledMessage.Platform {
 		case "discord":
-			config := platformConfig.(DiscordConfig)
+			config := platformConfig.(models.DiscordConfig)
 
 			channelId := config.ChannelId
 
-			if _, err := discord.DiscordBot.ChannelMessageSend(channelId, strings.Join(messages, "\n\n")); err != nil {
-				logText := fmt.Sprintf("Failed to send message to channel %s: %v", channelId, err)
-				database.DB.Exec("INSERT INTO scheduled_message_error_logs (scheduled_message_id, text, level) VALUES ($1, $2);", scheduledMessage.Id, logText, "ERROR")
+			err := h.discord.Send(ctx, channelId, strings.Join(messages, "\n\n"))
+			if errors.Is(err, notifier.ErrNotConfigured) || errors.Is(err, notifier.ErrUnavailable) {
+				// the next run sends the matching rows again
+				h.writeScheduledMessageLog(record, scheduledMessage.Id, scheduledMessageName, "WARN", fmt.Sprintf("Discord is unavailable, retrying on the next run: %v", err))
+				return
+			}
+			if err != nil {
+				status = "failed"
+				h.writeScheduledMessageLog(record, scheduledMessage.Id, scheduledMessageName, "ERROR", fmt.Sprintf("Failed to send message to channel %s: %v", channelId, err))
+				if err := h.store.ScheduledMessages.RecordExecution(record, h.store.DB, scheduledMessage.Id, "failed"); err != nil {
+					slog.ErrorContext(record, "failed to record execution", "scheduled_message", scheduledMessageName, "error", err)
+				}
 				return
 			}
 		}
-		if _, err := database.DB.Exec("INSERT INTO scheduled_message_execution_history(scheduled_message_id, status) VALUES ($1, $2);", scheduledMessage.Id, "success"); err != nil {
-			fmt.Println(err)
+		status = "success"
+		if err := h.store.ScheduledMessages.RecordExecution(record, h.store.DB, scheduledMessage.Id, "success"); err != nil {
+			slog.ErrorContext(record, "failed to record execution", "scheduled_message", scheduledMessageName, "error", err)
 		}
 
 	}
 
-	tx, err := database.DB.Begin()
+	tx, err := h.store.DB.Begin(ctx)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"status": "error", "message": "Failed to start transaction"})
@@ -685,7 +567,7 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	cronJobId, err := scheduler.Cron.AddFunc(cronSpec, sendMessage)
+	cronJobId, err := h.scheduler.Add(scheduledMessageName, cronSpec, sendMessage)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -694,31 +576,21 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	entryId.Store(int64(cronJobId))
 
-	if _, err := tx.Exec("INSERT INTO scheduled_message_state_history(scheduled_message_id, state) VALUES ($1, $2);", scheduledMessage.Id, "started"); err != nil {
-		scheduler.Cron.Remove(cronJobId)
+	if err := h.store.ScheduledMessages.Start(ctx, tx, scheduledMessage.Id, cronJobId); err != nil {
+		h.scheduler.Remove(cronJobId)
 
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to add column: %v", err),
-		})
-		return
-	}
-
-	if _, err := tx.Exec("INSERT INTO running_scheduled_messages(id, scheduled_message_id) VALUES ($1, $2);", int(cronJobId), scheduledMessage.Id); err != nil {
-		scheduler.Cron.Remove(cronJobId)
-
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to add column: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
 
 	if err := tx.Commit(); err != nil {
-		scheduler.Cron.Remove(cronJobId)
+		h.scheduler.Remove(cronJobId)
 
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -728,6 +600,14 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	prevRun, nextRun := h.scheduler.Runs(cronJobId)
+	h.publishScheduledMessageEvent(scheduledMessageName, models.ScheduledMessageEvent{
+		Event:   "state_changed",
+		State:   "started",
+		PrevRun: prevRun,
+		NextRun: nextRun,
+	})
+
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":  "success",
 		"message": "Scheduled message activated",
@@ -737,24 +617,18 @@ func EnableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // Disable Scheduled Message
 // --------------------
-func DisableScheduledMessage(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DisableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	ctx := r.Context()
 	scheduledMessageName := chi.URLParam(r, "scheduled-message-name")
 
-	var runningScheduledMessageId int
-	var scheduledMessageId int
-	err := database.DB.QueryRow(`
-		SELECT 
-			sm.id,
-			rsm.id
-		FROM scheduled_messages sm
-		LEFT JOIN running_scheduled_messages rsm ON sm.id = rsm.scheduled_message_id
-		WHERE name = $1
-	`, scheduledMessageName).Scan(&scheduledMessageId, &runningScheduledMessageId)
-
+	scheduledMessageId, runningScheduledMessageId, err := h.store.ScheduledMessages.Running(ctx, h.store.DB, scheduledMessageName)
+	if err == nil && runningScheduledMessageId == nil {
+		err = store.ErrNotFound
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == store.ErrNotFound {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]string{
 				"status":  "error",
@@ -771,9 +645,9 @@ func DisableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	scheduler.Cron.Remove(cron.EntryID(runningScheduledMessageId))
+	h.scheduler.Remove(*runningScheduledMessageId)
 
-	tx, err := database.DB.Begin()
+	tx, err := h.store.DB.Begin(ctx)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"status": "error", "message": "Failed to start transaction"})
@@ -781,20 +655,11 @@ func DisableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	if _, err := tx.Exec("DELETE FROM running_scheduled_messages WHERE id = $1", runningScheduledMessageId); err != nil {
+	if err := h.store.ScheduledMessages.Stop(ctx, tx, scheduledMessageId, *runningScheduledMessageId); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to remove running scheduled message: %v", err),
-		})
-		return
-	}
-
-	if _, err := tx.Exec("INSERT INTO scheduled_message_state_history(scheduled_message_id, state) VALUES ($1, $2);", scheduledMessageId, "stopped"); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to remove running scheduled message: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
@@ -808,6 +673,11 @@ func DisableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.publishScheduledMessageEvent(scheduledMessageName, models.ScheduledMessageEvent{
+		Event: "state_changed",
+		State: "stopped",
+	})
+
 	json.NewEncoder(w).Encode(map[string]any{
 		"status":  "success",
 		"message": "Scheduled message disabled",
@@ -821,131 +691,88 @@ func DisableScheduledMessage(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // Fetch Logs
 // --------------------
-func FetchLogs(w http.ResponseWriter, r *http.Request) {
+
+// FetchLogs returns a page of the logs of a scheduled message, newest first.
+// See parseLogQuery for the filters.
+func (h *Handler) FetchLogs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	scheduledMessageName := chi.URLParam(r, "scheduled-message-name")
 
-	query := `
-    SELECT 
-			smel.id,
-      smel.text,
-			smel.level,
-			smel.created_at
-    FROM scheduled_message_error_logs smel
-		LEFT JOIN scheduled_messages sm ON smel.scheduled_message_id = sm.id
-		WHERE sm.name = $1
-		ORDER BY smel.created_at ASC;
-		`
-
-	rows, err := database.DB.Query(query, scheduledMessageName)
+	scheduledMessageId, _, err := h.store.ScheduledMessages.Running(r.Context(), h.store.DB, scheduledMessageName)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		if err == store.ErrNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "error",
+				"message": "Scheduled message not found",
+			})
+			return
+		}
+
+		w.WriteHeader(queryErrorStatus(err))
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to query logs: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer rows.Close()
-
-	logs := []models.Log{}
 
-	for rows.Next() {
-		var log models.Log
-		if err := rows.Scan(&log.Id, &log.Text, &log.Level, &log.CreatedAt); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": fmt.Sprintf("Failed to scan log: %v", err),
-			})
-			return
-		}
-		logs = append(logs, log)
+	filter, err := parseLogQuery(scheduledMessageId, r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
 	}
 
-	if err := rows.Err(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	// one more log than the limit tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+
+	logs, cursors, err := h.store.Logs.List(r.Context(), h.store.DB, *filter)
+	if err != nil {
+		w.WriteHeader(queryErrorStatus(err))
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to read rows: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
 
+	meta := map[string]any{
+		"limit":       limit,
+		"next_cursor": nil,
+	}
+	if len(logs) > limit {
+		logs = logs[:limit]
+		meta["next_cursor"] = encodeLogCursor(cursors[limit-1])
+	}
+
 	json.NewEncoder(w).Encode(map[string]any{
 		"status": "success",
 		"data":   logs,
+		"meta":   meta,
 	})
-
 }
 
 // --------------------
 // Fetch Logs End
 // --------------------
 
-func FetchStateHistory(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) FetchStateHistory(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	scheduledMessageName := chi.URLParam(r, "scheduled-message-name")
 
-	query := `
-		SELECT
-			id,
-			state,
-			created_at
-		FROM (
-			SELECT
-				smsh.id,
-				smsh.state,
-				smsh.created_at
-			FROM scheduled_message_state_history smsh
-			LEFT JOIN scheduled_messages sm
-				ON smsh.scheduled_message_id = sm.id
-			WHERE sm.name = $1
-			ORDER BY smsh.created_at DESC
-			LIMIT 100
-		) latest
-		ORDER BY created_at ASC;
-		`
-
-	rows, err := database.DB.Query(query, scheduledMessageName)
+	data, err := h.store.ScheduledMessages.StateHistory(r.Context(), h.store.DB, scheduledMessageName)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to query logs: %v", err),
-		})
-		return
-	}
-	defer rows.Close()
-
-	type Data struct {
-		Id        int       `json:"id"`
-		State     string    `json:"state"`
-		CreatedAt time.Time `json:"created_at"`
-	}
-
-	data := []Data{}
-
-	for rows.Next() {
-		var d Data
-		if err := rows.Scan(&d.Id, &d.State, &d.CreatedAt); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": fmt.Sprintf("Failed to scan log: %v", err),
-			})
-			return
-		}
-		data = append(data, d)
-	}
-
-	if err := rows.Err(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to read rows: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"wolfscream/logging"
+	"wolfscream/models"
+	"wolfscream/websocket"
+)
+
+// EventHook is called with every scheduled message event after it was
+// published on the WebSocket topic.
+type EventHook func(name string, event models.ScheduledMessageEvent)
+
+// OnScheduledMessageEvent adds a hook to the scheduled message events. It
+// must be called before the handler serves requests.
+func (h *Handler) OnScheduledMessageEvent(hook EventHook) {
+	h.eventHooks = append(h.eventHooks, hook)
+}
+
+func (h *Handler) publishScheduledMessageEvent(name string, event models.ScheduledMessageEvent) {
+	event.CreatedAt = time.Now()
+	websocket.GetHub().Broadcast(websocket.ScheduledMessageTopic(name), event)
+
+	for _, hook := range h.eventHooks {
+		hook(name, event)
+	}
+}
+
+// writeScheduledMessageLog stores a log row with the run id of ctx, publishes
+// it to subscribers of the scheduled message and writes it to the server log.
+func (h *Handler) writeScheduledMessageLog(ctx context.Context, id int, name string, level string, text string) {
+	slog.Log(ctx, logging.ParseLevel(level), text, "scheduled_message", name)
+
+	entry, err := h.store.Logs.Write(ctx, h.store.DB, id, logging.RunID(ctx), level, text)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to write scheduled message log", "scheduled_message", name, "error", err)
+		return
+	}
+
+	h.publishScheduledMessageEvent(name, models.ScheduledMessageEvent{
+		Event: "log",
+		Log:   entry,
+	})
+}
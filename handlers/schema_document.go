@@ -0,0 +1,514 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"wolfscream/schemadoc"
+	"wolfscream/store"
+	"wolfscream/validator"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// schemaFormat returns the document format of a request, from the format
+// query parameter or else the Content-Type.
+func schemaFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+		return schemadoc.FormatYAML
+	}
+	return schemadoc.FormatJSON
+}
+
+// tableDefinition returns a registered table as a document table. It
+// returns store.ErrNotFound when the table isn't registered.
+func (h *Handler) tableDefinition(ctx context.Context, db store.DBTX, tableName string) (*schemadoc.Table, error) {
+	registered, err := h.store.Tables.Get(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := h.store.Columns.Describe(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := h.store.Indexes.List(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	description := ""
+	if registered.Description != nil {
+		description = *registered.Description
+	}
+
+	table := &schemadoc.Table{
+		Name:        tableName,
+		Description: description,
+		Columns:     []schemadoc.Column{},
+		Indexes:     []schemadoc.Index{},
+	}
+
+	for _, c := range columns {
+		column := schemadoc.Column{
+			Name:      c.Name,
+			Type:      c.Type,
+			Length:    c.Length,
+			Precision: c.Precision,
+			Scale:     c.Scale,
+			Values:    c.Values,
+			Nullable:  &c.Nullable,
+			Unique:    c.Unique,
+		}
+		if c.Check != nil {
+			column.Check = *c.Check
+		}
+		if c.References != nil {
+			column.References, column.OnDelete = c.References.Table, c.References.OnDelete
+		}
+		if text, ok := c.DefaultValue.(string); ok {
+			column.Default = schemadoc.DefaultValue(column.Spec(tableName), text)
+		}
+		table.Columns = append(table.Columns, column)
+	}
+
+	for _, index := range indexes {
+		table.Indexes = append(table.Indexes, schemadoc.Index{
+			Name:    index.Name,
+			Method:  index.Method,
+			Unique:  index.Unique,
+			Columns: index.Columns,
+		})
+	}
+
+	return table, nil
+}
+
+// --------------------
+// Export Table Schema
+// --------------------
+
+// ExportTableSchema returns a table, its columns, constraints and indexes as
+// a document that ApplySchema accepts. format is json, the default, or yaml.
+func (h *Handler) ExportTableSchema(w http.ResponseWriter, r *http.Request) {
+	tableName := chi.URLParam(r, "table-name")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = schemadoc.FormatJSON
+	}
+	if format != schemadoc.FormatJSON && format != schemadoc.FormatYAML {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Unknown format: %s", format),
+		})
+		return
+	}
+
+	table, err := h.tableDefinition(r.Context(), h.store.DB, tableName)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		writeTableColumnsError(w, tableName, err)
+		return
+	}
+
+	if format == schemadoc.FormatYAML {
+		w.Header().Set("Content-Type", "application/yaml")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, tableName, format))
+
+	doc := &schemadoc.Document{Tables: []schemadoc.Table{*table}}
+	if err := schemadoc.Encode(w, doc, format); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode schema", "table", tableName, "error", err)
+	}
+}
+
+// --------------------
+// Export Table Schema End
+// --------------------
+
+// --------------------
+// Apply Schema
+// --------------------
+
+// ApplySchema creates and migrates tables to match a document, sent as JSON
+// or YAML. Tables left out of the document are not touched.
+//
+// Query parameters:
+//
+//	dry_run  true returns the plan, and how the rows convert for type
+//	         changes, without changing anything
+//	prune    true drops the columns and indexes of the document's tables
+//	         that the document leaves out
+//
+// Table and column changes are applied in one transaction. Indexes are built
+// concurrently afterwards, so when an index fails the table changes are
+// already committed; the response lists the changes that were applied.
+func (h *Handler) ApplySchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Invalid request body",
+		})
+		return
+	}
+
+	doc, err := schemadoc.Parse(data, schemaFormat(r))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := validator.Validate.Struct(doc); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status": "error",
+			"errors": validator.FormatError(err),
+		})
+		return
+	}
+
+	if err := schemadoc.Validate(doc); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":  "error",
+			"message": "Invalid document",
+			"errors":  strings.Split(err.Error(), "\n"),
+		})
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	prune := r.URL.Query().Get("prune") == "true"
+
+	ctx := r.Context()
+
+	current := map[string]*schemadoc.Table{}
+	columns := map[string][]store.Column{}
+	declared := map[string]bool{}
+	for _, table := range doc.Tables {
+		declared[table.Name] = true
+
+		definition, err := h.tableDefinition(ctx, h.store.DB, table.Name)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err == nil {
+			columns[table.Name], err = h.store.Columns.Load(ctx, h.store.DB, table.Name)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+		current[table.Name] = definition
+	}
+
+	// references to tables outside the document must exist already
+	for _, table := range doc.Tables {
+		for _, column := range table.Columns {
+			if column.References == "" || declared[column.References] {
+				continue
+			}
+			target, err := h.store.Columns.Load(ctx, h.store.DB, column.References)
+			if err == nil && !hasIdColumn(target) {
+				err = newRequestError("Table %s has no id column", column.References)
+			} else if errors.Is(err, store.ErrNotFound) {
+				err = newRequestError("Table %s referenced by %s.%s does not exist", column.References, table.Name, column.Name)
+			}
+			if err != nil {
+				writeConstraintError(w, err)
+				return
+			}
+		}
+	}
+
+	changes := schemadoc.Plan(current, doc, prune)
+	conversions := map[string]*conversionReport{}
+
+	tx, err := h.store.DB.Begin(ctx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Failed to start transaction",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	applied := []schemadoc.Change{}
+	for _, change := range changes {
+		if change.Action == schemadoc.CreateIndex || change.Action == schemadoc.DropIndex {
+			continue
+		}
+
+		if dryRun {
+			if change.Action == schemadoc.AlterColumn && change.Changes("type") {
+				column := findTableColumn(columns[change.Table], change.Column)
+				report, err := h.changeColumnType(ctx, tx, change.Table, column, declaredColumn(doc, change).Spec(change.Table), onInvalidFail, true)
+				if err != nil {
+					writeApplyError(w, change, applied, err)
+					return
+				}
+				conversions[change.Table+"."+change.Column] = report
+			}
+			continue
+		}
+
+		report, err := h.applySchemaChange(ctx, tx, doc, columns, change)
+		if err != nil {
+			writeApplyError(w, change, applied, err)
+			return
+		}
+		if report != nil {
+			conversions[change.Table+"."+change.Column] = report
+		}
+		applied = append(applied, change)
+	}
+
+	if dryRun {
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":  "success",
+			"message": fmt.Sprintf("Dry run, %d changes planned", len(changes)),
+			"data": map[string]any{
+				"changes":     changes,
+				"conversions": conversions,
+			},
+		})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Failed to commit transaction",
+		})
+		return
+	}
+
+	for _, change := range changes {
+		switch change.Action {
+		case schemadoc.DropIndex:
+			err = h.store.Indexes.Drop(ctx, h.store.DB, change.Index)
+		case schemadoc.CreateIndex:
+			index := declaredIndex(doc, change)
+			err = h.store.Indexes.Create(ctx, h.store.DB, change.Table, &store.Index{
+				Name:    index.Name,
+				Method:  index.Method,
+				Unique:  index.Unique,
+				Columns: index.Columns,
+			})
+		default:
+			continue
+		}
+		if err != nil {
+			writeApplyError(w, change, applied, err)
+			return
+		}
+		applied = append(applied, change)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  "success",
+		"message": fmt.Sprintf("%d changes applied", len(applied)),
+		"data": map[string]any{
+			"changes":     applied,
+			"conversions": conversions,
+		},
+	})
+}
+
+// --------------------
+// Apply Schema End
+// --------------------
+
+// writeApplyError answers a change that failed, with the changes that were
+// committed before it.
+func writeApplyError(w http.ResponseWriter, change schemadoc.Change, applied []schemadoc.Change, err error) {
+	var conflict *constraintConflict
+	_, isRequestError := err.(*requestError)
+
+	status := queryErrorStatus(err)
+	switch {
+	case errors.As(err, &conflict):
+		status = http.StatusConflict
+	case isRequestError:
+		status = http.StatusBadRequest
+	}
+
+	// changes before an index change were committed, the others rolled back
+	if change.Action != schemadoc.CreateIndex && change.Action != schemadoc.DropIndex {
+		applied = []schemadoc.Change{}
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  "error",
+		"message": fmt.Sprintf("Failed to %s: %v", change, err),
+		"data": map[string]any{
+			"change":   change,
+			"conflict": conflict,
+			"applied":  applied,
+		},
+	})
+}
+
+func findTableColumn(columns []store.Column, name string) store.Column {
+	for _, column := range columns {
+		if column.Name == name {
+			return column
+		}
+	}
+	return store.Column{Name: name}
+}
+
+func declaredColumn(doc *schemadoc.Document, change schemadoc.Change) schemadoc.Column {
+	for _, table := range doc.Tables {
+		for _, column := range table.Columns {
+			if table.Name == change.Table && column.Name == change.Column {
+				return column
+			}
+		}
+	}
+	return schemadoc.Column{Name: change.Column}
+}
+
+func declaredIndex(doc *schemadoc.Document, change schemadoc.Change) schemadoc.Index {
+	for _, table := range doc.Tables {
+		for _, index := range table.Indexes {
+			if table.Name == change.Table && index.Name == change.Index {
+				return index
+			}
+		}
+	}
+	return schemadoc.Index{Name: change.Index}
+}
+
+func declaredDescription(doc *schemadoc.Document, tableName string) string {
+	for _, table := range doc.Tables {
+		if table.Name == tableName {
+			return table.Description
+		}
+	}
+	return ""
+}
+
+// applySchemaChange applies a table or column change of a plan inside tx.
+// columns holds the columns of the tables that existed before the plan.
+func (h *Handler) applySchemaChange(ctx context.Context, tx store.DBTX, doc *schemadoc.Document, columns map[string][]store.Column, change schemadoc.Change) (*conversionReport, error) {
+	switch change.Action {
+	case schemadoc.CreateTable:
+		return nil, h.store.Tables.Create(ctx, tx, change.Table, "")
+
+	case schemadoc.UpdateTable:
+		return nil, h.store.Tables.SetDescription(ctx, tx, change.Table, declaredDescription(doc, change.Table))
+
+	case schemadoc.AddColumn:
+		declared := declaredColumn(doc, change)
+		column := store.Column{Name: declared.Name, Spec: declared.Spec(change.Table)}
+		if err := h.addColumn(ctx, tx, change.Table, column, declared.Default); err != nil {
+			return nil, err
+		}
+		return nil, h.applyConstraints(ctx, tx, change.Table, column, declaredConstraints(declared, nil))
+
+	case schemadoc.DropColumn:
+		return nil, h.store.Columns.Drop(ctx, tx, change.Table, change.Column)
+
+	case schemadoc.AlterColumn:
+		declared := declaredColumn(doc, change)
+		column := findTableColumn(columns[change.Table], change.Column)
+
+		var report *conversionReport
+		if change.Changes("type") {
+			// a check or reference that is being replaced mustn't hold up the
+			// conversion
+			empty := ""
+			removed := columnConstraints{}
+			if change.Changes("check") {
+				removed.Check = &empty
+			}
+			if change.Changes("references") {
+				removed.References = &empty
+			}
+			if err := h.applyConstraints(ctx, tx, change.Table, column, removed); err != nil {
+				return nil, err
+			}
+
+			var err error
+			report, err = h.changeColumnType(ctx, tx, change.Table, column, declared.Spec(change.Table), onInvalidFail, false)
+			if err != nil {
+				return nil, err
+			}
+			column.Spec = declared.Spec(change.Table)
+		}
+
+		if change.Changes("default") {
+			if err := h.setColumnDefault(ctx, tx, change.Table, column, declared.Default); err != nil {
+				return nil, err
+			}
+		}
+
+		return report, h.applyConstraints(ctx, tx, change.Table, column, declaredConstraints(declared, &change))
+	}
+
+	return nil, fmt.Errorf("unknown change %s", change.Action)
+}
+
+// declaredConstraints returns the constraints of a declared column. With a
+// change only the constraints it touches are set, for a new column the ones
+// that differ from a plain nullable column.
+func declaredConstraints(column schemadoc.Column, change *schemadoc.Change) columnConstraints {
+	set := func(field string, declared bool) bool {
+		if change == nil {
+			return declared
+		}
+		return change.Changes(field)
+	}
+
+	nullable := column.IsNullable()
+	c := columnConstraints{}
+
+	if set("nullable", !nullable) {
+		c.Nullable = &nullable
+	}
+	if set("unique", column.Unique) {
+		c.Unique = &column.Unique
+	}
+	if set("check", column.Check != "") {
+		c.Check = &column.Check
+	}
+	if set("references", column.References != "") {
+		c.References = &column.References
+		if column.References != "" {
+			c.OnDelete = &column.OnDelete
+		}
+	}
+
+	return c
+}
@@ -4,18 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
-	"wolfscream/database"
+	"wolfscream/models"
+	"wolfscream/store"
 	"wolfscream/validator"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/lib/pq"
 )
 
 // --------------------
 // Create Table
 // --------------------
-func CreateTable(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateTable(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	type CreateTableBody struct {
@@ -43,7 +42,9 @@ func CreateTable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := database.DB.Begin()
+	ctx := r.Context()
+
+	tx, err := h.store.DB.Begin(ctx)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -54,22 +55,11 @@ func CreateTable(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	query := fmt.Sprintf(`CREATE TABLE %s ();`, pq.QuoteIdentifier(body.Name))
-
-	if _, err := tx.Exec(query); err != nil {
+	if err := h.store.Tables.Create(ctx, tx, body.Name, body.Description); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to create table: %v", err),
-		})
-		return
-	}
-
-	if _, err := tx.Exec(`INSERT INTO "user_defined_table" ("name", "description") VALUES ($1, $2);`, body.Name, body.Description); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to insert table: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
@@ -96,48 +86,77 @@ func CreateTable(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // List Tables
 // --------------------
-func ListTables(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListTables(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	query := "SELECT id, name, description FROM user_defined_table ORDER BY created_at ASC;"
-
-	rows, err := database.DB.Query(query)
+	tables, err := h.store.Tables.List(r.Context(), h.store.DB)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to query columns: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer rows.Close()
 
-	type Table struct {
-		Id          int     `json:"id"`
-		Name        string  `json:"name"`
-		Description *string `json:"description"`
-	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": "success",
+		"data":   tables,
+	})
+}
+
+// --------------------
+// List Tables End
+// --------------------
 
-	tables := []Table{}
+// --------------------
+// Describe Table
+// --------------------
 
-	for rows.Next() {
-		var table Table
-		if err := rows.Scan(&table.Id, &table.Name, &table.Description); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+// DescribeTable returns a table with its columns and indexes.
+func (h *Handler) DescribeTable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx := r.Context()
+	tableName := chi.URLParam(r, "table-name")
+
+	type TableDescription struct {
+		models.Table
+		Columns []store.ColumnDescription `json:"columns"`
+		Indexes []store.Index             `json:"indexes"`
+	}
+
+	var table TableDescription
+	found, err := h.store.Tables.Get(ctx, h.store.DB, tableName)
+	if err != nil {
+		if err == store.ErrNotFound {
+			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]string{
 				"status":  "error",
-				"message": fmt.Sprintf("Failed to scan table: %v", err),
+				"message": fmt.Sprintf("Table %s does not exist", tableName),
 			})
 			return
 		}
-		tables = append(tables, table)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Failed to query table: %v", err),
+		})
+		return
 	}
 
-	if err := rows.Err(); err != nil {
+	table.Table = *found
+
+	table.Columns, err = h.store.Columns.Describe(ctx, h.store.DB, tableName)
+	if err == nil {
+		table.Indexes, err = h.store.Indexes.List(ctx, h.store.DB, tableName)
+	}
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to read rows: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
@@ -145,18 +164,18 @@ func ListTables(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]any{
 		"status": "success",
-		"data":   tables,
+		"data":   table,
 	})
 }
 
 // --------------------
-// List Tables End
+// Describe Table End
 // --------------------
 
 // --------------------
 // Update Table
 // --------------------
-func UpdateTable(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateTable(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	tableName := chi.URLParam(r, "table-name")
@@ -195,7 +214,9 @@ func UpdateTable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := database.DB.Begin()
+	ctx := r.Context()
+
+	tx, err := h.store.DB.Begin(ctx)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -206,22 +227,17 @@ func UpdateTable(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	if body.Name != nil && *body.Name != tableName {
-		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s RENAME TO %s;`, pq.QuoteIdentifier(tableName), pq.QuoteIdentifier(*body.Name))); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": fmt.Sprintf("Failed to create table: %v", err),
-			})
-			return
-		}
+	if body.Description != nil {
+		err = h.store.Tables.SetDescription(ctx, tx, tableName, *body.Description)
 	}
-
-	if _, err := tx.Exec(`UPDATE user_defined_table SET name = COALESCE($1, name), description = COALESCE($2, description) WHERE name = $3;`, body.Name, body.Description, tableName); err != nil {
+	if err == nil && body.Name != nil && *body.Name != tableName {
+		err = h.store.Tables.Rename(ctx, tx, tableName, *body.Name)
+	}
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to update table: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
@@ -248,7 +264,7 @@ func UpdateTable(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // Drop Table
 // --------------------
-func DropTable(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DropTable(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	tableName := chi.URLParam(r, "table-name")
@@ -263,7 +279,9 @@ func DropTable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := database.DB.Begin()
+	ctx := r.Context()
+
+	tx, err := h.store.DB.Begin(ctx)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -275,21 +293,11 @@ func DropTable(w http.ResponseWriter, r *http.Request) {
 
 	defer tx.Rollback()
 
-	dropQuery := fmt.Sprintf("DROP TABLE %s", tableName)
-	if _, err := tx.Exec(dropQuery); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to drop table: %v", err),
-		})
-		return
-	}
-
-	if _, err := tx.Exec("DELETE FROM user_defined_table WHERE name = $1", tableName); err != nil {
+	if err := h.store.Tables.Drop(ctx, tx, tableName); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to delete metadata: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
@@ -317,63 +325,65 @@ func DropTable(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // Get Data
 // --------------------
-func GetData(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetData(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	table := chi.URLParam(r, "table")
+	table := chi.URLParam(r, "table-name")
 
-	rows, err := database.DB.Query(fmt.Sprintf("SELECT * FROM %s;", pq.QuoteIdentifier(table)))
+	columns, err := h.store.Columns.Load(r.Context(), h.store.DB, table)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeTableColumnsError(w, table, err)
+		return
+	}
+
+	q, err := parseDataQuery(table, columns, r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to fetch data from table '%s': %v", table, err),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer rows.Close()
 
-	columns, err := rows.Columns()
+	total, err := h.store.Data.Count(r.Context(), h.store.DB, q)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(queryErrorStatus(err))
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Unable to read table structure: %v", err),
+			"message": fmt.Sprintf("Failed to count data from table '%s': %v", table, err),
 		})
 		return
 	}
 
-	results := []map[string]any{}
-
-	for rows.Next() {
-		values := make([]any, len(columns))
-		valuePtrs := make([]any, len(columns))
-
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuePtrs...); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": fmt.Sprintf("Failed to scan: %v", err),
-			})
-			return
-		}
-
-		rowMap := map[string]any{}
-		for i, col := range columns {
-			rowMap[col] = values[i]
-		}
+	results, next, err := h.store.Data.Page(r.Context(), h.store.DB, q)
+	if err != nil {
+		w.WriteHeader(queryErrorStatus(err))
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Failed to fetch data from table '%s': %v", table, err),
+		})
+		return
+	}
 
-		results = append(results, rowMap)
+	meta := map[string]any{
+		"total": total,
+		"limit": q.Limit,
+	}
+	if q.Cursor == nil {
+		meta["offset"] = q.Offset
+	}
+	if next != nil {
+		meta["next_cursor"] = encodeCursor(*next)
+	} else {
+		meta["next_cursor"] = nil
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]any{
 		"status": "success",
 		"data":   results,
+		"meta":   meta,
 	})
 }
 
@@ -384,17 +394,23 @@ func GetData(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // Insert Data
 // --------------------
-func InsertData(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) InsertData(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	table := chi.URLParam(r, "table")
+	table := chi.URLParam(r, "table-name")
 
-	var body map[string]any
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+	columns, err := h.store.Columns.Load(r.Context(), h.store.DB, table)
+	if err != nil {
+		writeTableColumnsError(w, table, err)
+		return
+	}
+
+	body, err := decodeRowBody(r)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": "Invalid JSON",
+			"message": err.Error(),
 		})
 		return
 	}
@@ -408,39 +424,67 @@ func InsertData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := database.DB.Begin()
+	values, err := coerceValues(columns, body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": "Failed to start transaction",
+			"message": err.Error(),
 		})
 		return
 	}
-	defer tx.Rollback()
 
-	columns := []string{}
-	placeholders := []string{}
-	values := []any{}
-	i := 1
+	row, err := h.store.Data.Insert(r.Context(), h.store.DB, table, columns, values)
+	if err != nil {
+		w.WriteHeader(queryErrorStatus(err))
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
 
-	for key, val := range body {
-		columns = append(columns, key)
-		placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-		values = append(values, val)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  "success",
+		"message": "Data inserted",
+		"data":    row,
+	})
+}
+
+// --------------------
+// Insert Data End
+// --------------------
 
-		i++
+// --------------------
+// Get Row
+// --------------------
+func (h *Handler) GetRow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	table := chi.URLParam(r, "table-name")
+
+	columns, err := h.store.Columns.Load(r.Context(), h.store.DB, table)
+	if err != nil {
+		writeTableColumnsError(w, table, err)
+		return
 	}
 
-	query := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES (%s);",
-		table,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "),
-	)
+	id, err := rowIdParam(r)
+	if err == nil && !hasIdColumn(columns) {
+		err = newRequestError("Table %s has no id column", table)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
 
-	if _, err := tx.Exec(query, values...); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	fields, err := parseFields(columns, r.URL.Query().Get("fields"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
 			"message": err.Error(),
@@ -448,36 +492,157 @@ func InsertData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := tx.Commit(); err != nil {
+	row, err := h.store.Data.Get(r.Context(), h.store.DB, table, columns, fields, id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "error",
+				"message": "Row not found",
+			})
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": "Failed to commit transaction",
+			"message": fmt.Sprintf("Failed to fetch row: %v", err),
 		})
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": "success",
+		"data":   row,
+	})
+}
+
+// --------------------
+// Get Row End
+// --------------------
+
+// --------------------
+// Update Row
+// --------------------
+
+// UpdateRow handles PATCH, which only writes the columns present in the body,
+// and PUT, which replaces the row and resets the missing columns to their
+// default.
+func (h *Handler) UpdateRow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	table := chi.URLParam(r, "table-name")
+	replace := r.Method == http.MethodPut
+
+	columns, err := h.store.Columns.Load(r.Context(), h.store.DB, table)
+	if err != nil {
+		writeTableColumnsError(w, table, err)
+		return
+	}
+
+	id, err := rowIdParam(r)
+	if err == nil && !hasIdColumn(columns) {
+		err = newRequestError("Table %s has no id column", table)
+	}
+
+	var body map[string]any
+	if err == nil {
+		body, err = decodeRowBody(r)
+	}
+
+	var values map[string]any
+	if err == nil {
+		dropMatchingId(body, id)
+		values, err = coerceValues(columns, body)
+	}
+
+	if err == nil && len(values) == 0 && !replace {
+		err = newRequestError("Empty body")
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if len(values) == 0 && !hasWritableColumn(columns) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Table %s has no writable columns", table),
+		})
+		return
+	}
+
+	row, err := h.store.Data.Update(r.Context(), h.store.DB, table, columns, id, values, replace)
+	if err != nil {
+		if err == store.ErrNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "error",
+				"message": "Row not found",
+			})
+			return
+		}
+		w.WriteHeader(queryErrorStatus(err))
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": fmt.Sprintf("Failed to update row: %v", err),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
 		"status":  "success",
-		"message": "Data inserted",
+		"message": "Row updated successfully",
+		"data":    row,
 	})
 }
 
 // --------------------
-// Insert Data End
+// Update Row End
 // --------------------
 
 // --------------------
 // Delete Data
 // --------------------
-func DeleteData(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteData(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	columnId := chi.URLParam(r, "columnId")
-	table := chi.URLParam(r, "table")
+	table := chi.URLParam(r, "table-name")
+
+	columns, err := h.store.Columns.Load(r.Context(), h.store.DB, table)
+	if err != nil {
+		writeTableColumnsError(w, table, err)
+		return
+	}
+
+	id, err := rowIdParam(r)
+	if err == nil && !hasIdColumn(columns) {
+		err = newRequestError("Table %s has no id column", table)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1;", table)
-	if _, err := database.DB.Exec(query, columnId); err != nil {
+	if err := h.store.Data.Delete(r.Context(), h.store.DB, table, id); err != nil {
+		if err == store.ErrNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "error",
+				"message": "Row not found",
+			})
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
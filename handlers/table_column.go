@@ -0,0 +1,21 @@
+package handlers
+
+import "wolfscream/store"
+
+// idColumn is the primary key CreateTable adds to every table. It isn't
+// registered in user_defined_column and can't be written through the API.
+const idColumn = store.IdColumn
+
+func hasIdColumn(columns []store.Column) bool {
+	return len(columns) > 0 && columns[0].Managed
+}
+
+// hasWritableColumn reports whether a column can be written through the API.
+func hasWritableColumn(columns []store.Column) bool {
+	for _, column := range columns {
+		if !column.Managed {
+			return true
+		}
+	}
+	return false
+}
@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"wolfscream/database"
-	"wolfscream/models"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -15,7 +13,7 @@ type AddTemplateBody struct {
 	Text string `json:"text"`
 }
 
-func AddTemplate(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AddTemplate(w http.ResponseWriter, r *http.Request) {
 	var body AddTemplateBody
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -27,8 +25,7 @@ func AddTemplate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := "INSERT INTO message_templates(name, text) VALUES ($1, $2);"
-	if _, err := database.DB.Exec(query, body.Name, body.Text); err != nil {
+	if err := h.store.Templates.Create(r.Context(), h.store.DB, body.Name, body.Text); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
@@ -46,45 +43,18 @@ func AddTemplate(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // List Message Templates
 // --------------------
-func ListMessageTemplates(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListMessageTemplates(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	query := "SELECT id, name, text, description, created_at FROM message_templates;"
-
-	rows, err := database.DB.Query(query)
+	messageTemplates, err := h.store.Templates.List(r.Context(), h.store.DB)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
-			"message": fmt.Sprintf("Failed to query message templates: %v", err),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer rows.Close()
-
-	messageTemplates := []models.MessageTemplate{}
-
-	for rows.Next() {
-		var messageTemplate models.MessageTemplate
-		if err := rows.Scan(&messageTemplate.Id, &messageTemplate.Name, &messageTemplate.Text, &messageTemplate.Description, &messageTemplate.CreatedAt); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "error",
-				"message": fmt.Sprintf("Failed to scan column: %v", err),
-			})
-			return
-		}
-		messageTemplates = append(messageTemplates, messageTemplate)
-	}
-
-	if err := rows.Err(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "error",
-			"message": fmt.Sprintf("Failed to read rows: %v", err),
-		})
-    return
-  }
 
 	json.NewEncoder(w).Encode(map[string]any{
 		"status":  "success",
@@ -98,12 +68,12 @@ func ListMessageTemplates(w http.ResponseWriter, r *http.Request) {
 // --------------------
 // Delete Message Template
 // --------------------
-func DeleteMessageTemplate(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteMessageTemplate(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	messageTemplateId := chi.URLParam(r, "messageTemplateId")
 
-	if _, err := database.DB.Exec("DELETE FROM message_templates WHERE id = $1", messageTemplateId); err != nil {
+	if err := h.store.Templates.Delete(r.Context(), h.store.DB, messageTemplateId); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "error",
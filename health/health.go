@@ -0,0 +1,226 @@
+// Package health serves the probes of the orchestrator: /healthz tells the
+// process is alive, /readyz that its dependencies are usable and /version
+// what is running.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"wolfscream/buildinfo"
+	"wolfscream/discord"
+	"wolfscream/migrations"
+	"wolfscream/scheduler"
+)
+
+// checkTimeout bounds every readiness check, a probe has to answer quickly.
+const checkTimeout = 2 * time.Second
+
+// Check is a dependency readiness depends on. Run returns a short detail
+// about the dependency. A failing check that isn't Critical is reported but
+// leaves the instance ready, degraded.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) (string, error)
+}
+
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+const (
+	StatusOK       = "ok"
+	StatusFailed   = "failed"
+	StatusReady    = "ready"
+	StatusDegraded = "degraded"
+	StatusNotReady = "not_ready"
+)
+
+type Handler struct {
+	checks    []Check
+	scheduler *scheduler.Scheduler
+}
+
+// New returns the probes of the server. Postgres, the scheduler and the
+// migrations are critical. Discord is optional, so a Discord outage only
+// degrades the instance. The check only reads the state of the connection, a
+// probe doesn't connect the bot.
+func New(db *sql.DB, bot *discord.Client, sched *scheduler.Scheduler) *Handler {
+	return &Handler{
+		scheduler: sched,
+		checks: []Check{
+			{
+				Name:     "postgres",
+				Critical: true,
+				Run: func(ctx context.Context) (string, error) {
+					return "", db.PingContext(ctx)
+				},
+			},
+			{
+				Name:     "migrations",
+				Critical: true,
+				Run: func(ctx context.Context) (string, error) {
+					pending, err := migrations.Pending(ctx, db)
+					if err != nil {
+						return "", err
+					}
+					if pending > 0 {
+						return "", fmt.Errorf("%d migrations pending", pending)
+					}
+					return "current", nil
+				},
+			},
+			{
+				Name:     "scheduler",
+				Critical: true,
+				Run: func(ctx context.Context) (string, error) {
+					if !sched.Running() {
+						return "", errors.New("scheduler is stopped")
+					}
+					return fmt.Sprintf("%d jobs scheduled", sched.Entries()), nil
+				},
+			},
+			{
+				Name: "discord",
+				Run: func(ctx context.Context) (string, error) {
+					status := bot.Status()
+					if status.Error != "" {
+						return status.State, errors.New(status.Error)
+					}
+					return status.State, nil
+				},
+			},
+		},
+	}
+}
+
+// --------------------
+// Healthz
+// --------------------
+
+// Healthz answers as long as the process serves requests. It checks no
+// dependency, a database outage must not get the process restarted.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": StatusOK,
+		"uptime": buildinfo.Uptime().Round(time.Second).String(),
+	})
+}
+
+// --------------------
+// Healthz End
+// --------------------
+
+// --------------------
+// Readyz
+// --------------------
+
+// Readyz runs every check and answers 503 when a critical one fails.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	results := runChecks(r.Context(), h.checks)
+
+	status := StatusReady
+	for _, result := range results {
+		if result.Status == StatusOK {
+			continue
+		}
+		if result.Critical {
+			status = StatusNotReady
+			break
+		}
+		status = StatusDegraded
+	}
+
+	if status == StatusNotReady {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": status,
+		"checks": results,
+	})
+}
+
+// --------------------
+// Readyz End
+// --------------------
+
+// runChecks runs the checks concurrently. A check that doesn't return within
+// checkTimeout is reported as failed.
+func runChecks(ctx context.Context, checks []Check) []CheckResult {
+	results := make([]CheckResult, len(checks))
+	done := make(chan struct{}, len(checks))
+
+	for i, check := range checks {
+		go func() {
+			defer func() { done <- struct{}{} }()
+
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			type outcome struct {
+				detail string
+				err    error
+			}
+			finished := make(chan outcome, 1)
+			go func() {
+				detail, err := check.Run(ctx)
+				finished <- outcome{detail, err}
+			}()
+
+			result := CheckResult{Name: check.Name, Status: StatusOK, Critical: check.Critical}
+			select {
+			case o := <-finished:
+				result.Detail = o.detail
+				if o.err != nil {
+					result.Status, result.Error = StatusFailed, o.err.Error()
+				}
+			case <-ctx.Done():
+				result.Status, result.Error = StatusFailed, "timed out"
+			}
+			results[i] = result
+		}()
+	}
+
+	for range checks {
+		<-done
+	}
+	return results
+}
+
+// --------------------
+// Version
+// --------------------
+
+// Version returns the build of the binary, the uptime and the jobs of the
+// scheduler.
+func (h *Handler) Version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uptime := buildinfo.Uptime()
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"build":          buildinfo.Get(),
+		"uptime":         uptime.Round(time.Second).String(),
+		"uptime_seconds": int64(uptime.Seconds()),
+		"scheduled_jobs": h.scheduler.Entries(),
+		"running_jobs":   h.scheduler.RunningJobs(),
+	})
+}
+
+// --------------------
+// Version End
+// --------------------
@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wolfscream/discord"
+	"wolfscream/notifier"
+)
+
+func check(name string, critical bool, err error) Check {
+	return Check{
+		Name:     name,
+		Critical: critical,
+		Run: func(ctx context.Context) (string, error) {
+			return "", err
+		},
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	down := errors.New("down")
+
+	tests := []struct {
+		name   string
+		checks []Check
+		code   int
+		status string
+	}{
+		{"all ok", []Check{check("postgres", true, nil), check("discord", false, nil)}, http.StatusOK, StatusReady},
+		{"optional failed", []Check{check("postgres", true, nil), check("discord", false, down)}, http.StatusOK, StatusDegraded},
+		{"critical failed", []Check{check("postgres", true, down), check("discord", false, down)}, http.StatusServiceUnavailable, StatusNotReady},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := &Handler{checks: test.checks}
+
+			w := httptest.NewRecorder()
+			h.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			var body struct {
+				Status string        `json:"status"`
+				Checks []CheckResult `json:"checks"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+
+			if w.Code != test.code || body.Status != test.status {
+				t.Errorf("got %d %s, want %d %s", w.Code, body.Status, test.code, test.status)
+			}
+			if len(body.Checks) != len(test.checks) {
+				t.Fatalf("got %d checks, want %d", len(body.Checks), len(test.checks))
+			}
+			for i, result := range body.Checks {
+				if result.Name != test.checks[i].Name {
+					t.Errorf("check %d is %s, want %s", i, result.Name, test.checks[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestRunChecksTimesOut(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	results := runChecks(ctx, []Check{{
+		Name: "stuck",
+		Run: func(ctx context.Context) (string, error) {
+			<-blocked
+			return "", nil
+		},
+	}})
+
+	if results[0].Status != StatusFailed || results[0].Error != "timed out" {
+		t.Errorf("got %+v, want a timed out failure", results[0])
+	}
+}
+
+func TestDiscordCheckDoesNotConnect(t *testing.T) {
+	for _, c := range New(nil, discord.New("token"), nil).checks {
+		if c.Name != "discord" {
+			continue
+		}
+		detail, err := c.Run(context.Background())
+		if err != nil || detail != notifier.StateIdle {
+			t.Errorf("discord check = %q, %v, want the idle bot left unconnected", detail, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package importer validates the records of a CSV, JSON or NDJSON body
+// against the columns of a user defined table and writes them with a
+// store.RowWriter.
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+
+	"wolfscream/datatype"
+	"wolfscream/store"
+)
+
+// MaxErrors is how many row errors a Result keeps, the rows failing after
+// that are only counted.
+const MaxErrors = 100
+
+type Mode string
+
+const (
+	// Atomic imports nothing if a row is invalid.
+	Atomic Mode = "atomic"
+	// Skip imports the valid rows and reports the others.
+	Skip Mode = "skip"
+)
+
+type RowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+type Result struct {
+	Inserted int
+	Failed   int
+	Errors   []RowError
+}
+
+func (r *Result) fail(rowError RowError) {
+	r.Failed++
+	if len(r.Errors) < MaxErrors {
+		r.Errors = append(r.Errors, rowError)
+	}
+}
+
+// Run writes the records of reader into writer. A record only writes the
+// columns it has so the others get their default: a missing JSON key, or an
+// empty CSV field of a column that isn't a string. An explicit JSON null is
+// written as NULL.
+//
+// In Atomic mode the records after the first invalid one are only validated
+// and the writer isn't flushed, the caller is expected to roll back. In Skip
+// mode the store.RowErrors returned by the writer are reported as invalid
+// rows. The returned error is an *InputError when the body can't be imported
+// at all, any other error comes from the writer.
+func Run(ctx context.Context, reader Reader, columns []store.Column, mode Mode, writer store.RowWriter) (Result, error) {
+	result := Result{Errors: []RowError{}}
+
+	byName := map[string]store.Column{}
+	for _, column := range columns {
+		byName[column.Name] = column
+	}
+	_, isCSV := reader.(*csvReader)
+
+	var known map[string]bool
+	for row := 1; ; row++ {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				result.fail(RowError{Row: row, Message: parseErr.Err.Error()})
+				continue
+			}
+			return result, inputError("Failed to read row %d: %v", row, err)
+		}
+
+		if known == nil {
+			known = map[string]bool{}
+			for _, name := range reader.Columns() {
+				column, ok := byName[name]
+				if !ok {
+					return result, inputError("Unknown column: %s", name)
+				}
+				if column.Managed {
+					return result, inputError("Column %s is managed and can't be written", name)
+				}
+				known[name] = true
+			}
+		}
+
+		names, values, rowError := rowValues(record, reader.Columns(), known, byName, isCSV)
+		if rowError != nil {
+			rowError.Row = row
+			result.fail(*rowError)
+			continue
+		}
+
+		// in atomic mode nothing gets committed once a row failed
+		if mode == Atomic && result.Failed > 0 {
+			continue
+		}
+
+		if err := writer.Insert(ctx, names, values); err != nil {
+			var storeErr *store.RowError
+			if mode == Skip && errors.As(err, &storeErr) {
+				result.fail(RowError{Row: row, Column: storeErr.Column, Message: storeErr.Err.Error()})
+				continue
+			}
+			return result, err
+		}
+		result.Inserted++
+	}
+
+	if mode == Atomic && result.Failed > 0 {
+		result.Inserted = 0
+		return result, nil
+	}
+
+	return result, writer.Flush(ctx)
+}
+
+// rowValues coerces the fields of record into the values of their columns,
+// in the order of order, leaving out the fields that take the default.
+func rowValues(record map[string]any, order []string, known map[string]bool, byName map[string]store.Column, isCSV bool) ([]string, []any, *RowError) {
+	for name := range record {
+		if !known[name] {
+			return nil, nil, &RowError{Column: name, Message: "unknown column"}
+		}
+	}
+
+	var (
+		names  []string
+		values []any
+	)
+	for _, name := range order {
+		value, ok := record[name]
+		if !ok {
+			continue
+		}
+
+		column := byName[name]
+		var (
+			coerced any
+			err     error
+		)
+		if text, ok := value.(string); ok && isCSV {
+			if text == "" && column.Type != "text" && column.Type != "varchar" {
+				continue
+			}
+			coerced, err = datatype.CoerceText(column.Spec, text)
+		} else {
+			coerced, err = datatype.Coerce(column.Spec, value)
+		}
+		if err != nil {
+			return nil, nil, &RowError{Column: name, Message: err.Error()}
+		}
+
+		names = append(names, name)
+		values = append(values, coerced)
+	}
+
+	return names, values, nil
+}
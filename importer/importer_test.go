@@ -0,0 +1,353 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"wolfscream/datatype"
+	"wolfscream/store"
+)
+
+type insert struct {
+	columns []string
+	values  []any
+}
+
+// fakeWriter records the inserted rows. reject returns the error of a row,
+// or nil to accept it.
+type fakeWriter struct {
+	rows    []insert
+	flushed bool
+	reject  func(columns []string, values []any) error
+}
+
+func (f *fakeWriter) Insert(ctx context.Context, columns []string, values []any) error {
+	if f.reject != nil {
+		if err := f.reject(columns, values); err != nil {
+			return err
+		}
+	}
+	f.rows = append(f.rows, insert{columns, values})
+	return nil
+}
+
+func (f *fakeWriter) Flush(ctx context.Context) error {
+	f.flushed = true
+	return nil
+}
+
+func readAll(t *testing.T, reader Reader) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		mapping map[string]string
+		columns []string
+		records []map[string]any
+	}{
+		{
+			name:    "header",
+			body:    "name, age\na,1\n\"b,c\",\n",
+			columns: []string{"name", "age"},
+			records: []map[string]any{{"name": "a", "age": "1"}, {"name": "b,c", "age": ""}},
+		},
+		{
+			name:    "mapping",
+			body:    "Full Name,Age,Notes\na,1,x\n",
+			mapping: map[string]string{"Full Name": "name", "Age": "age", "Notes": ""},
+			columns: []string{"name", "age"},
+			records: []map[string]any{{"name": "a", "age": "1"}},
+		},
+		{
+			name:    "header only",
+			body:    "name\n",
+			columns: []string{"name"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := NewCSV(strings.NewReader(test.body), test.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			records := readAll(t, reader)
+			if !reflect.DeepEqual(records, test.records) {
+				t.Errorf("records = %v, want %v", records, test.records)
+			}
+			if !reflect.DeepEqual(reader.Columns(), test.columns) {
+				t.Errorf("columns = %v, want %v", reader.Columns(), test.columns)
+			}
+		})
+	}
+}
+
+func TestCSVWithoutHeader(t *testing.T) {
+	_, err := NewCSV(strings.NewReader(""), nil)
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Fatalf("err = %v, want an InputError", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		columns []string
+		want    []string
+		records []map[string]any
+	}{
+		{
+			name:    "array",
+			body:    ` [{"b": 1, "a": "x"}, {"a": null}]`,
+			want:    []string{"a", "b"},
+			records: []map[string]any{{"a": "x", "b": json.Number("1")}, {"a": nil}},
+		},
+		{
+			name:    "ndjson",
+			body:    "{\"a\": 1.5}\n\n{\"a\": [\"x\"]}\n",
+			want:    []string{"a"},
+			records: []map[string]any{{"a": json.Number("1.5")}, {"a": []any{"x"}}},
+		},
+		{
+			name:    "columns",
+			body:    `{"a": 1}`,
+			columns: []string{"a", "b"},
+			want:    []string{"a", "b"},
+			records: []map[string]any{{"a": json.Number("1")}},
+		},
+		{
+			name: "empty array",
+			body: `[]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := NewJSON(strings.NewReader(test.body), test.columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			records := readAll(t, reader)
+			if !reflect.DeepEqual(records, test.records) {
+				t.Errorf("records = %v, want %v", records, test.records)
+			}
+			if !reflect.DeepEqual(reader.Columns(), test.want) {
+				t.Errorf("columns = %v, want %v", reader.Columns(), test.want)
+			}
+		})
+	}
+}
+
+func TestJSONEmptyBody(t *testing.T) {
+	_, err := NewJSON(strings.NewReader(" \n"), nil)
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Fatalf("err = %v, want an InputError", err)
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	mapping, err := ParseMapping(" Full Name : name,Notes:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"Full Name": "name", "Notes": ""}
+	if !reflect.DeepEqual(mapping, want) {
+		t.Errorf("mapping = %v, want %v", mapping, want)
+	}
+
+	if _, err := ParseMapping("name"); err == nil {
+		t.Error("a pair without a colon was accepted")
+	}
+}
+
+var columns = []store.Column{
+	{Name: "name", Spec: datatype.Spec{Type: "text"}},
+	{Name: "age", Spec: datatype.Spec{Type: "int4"}},
+	{Name: "created_by", Spec: datatype.Spec{Type: "text"}, Managed: true},
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		reader   func() (Reader, error)
+		mode     Mode
+		reject   func(columns []string, values []any) error
+		rows     []insert
+		flushed  bool
+		inserted int
+		errors   []RowError
+	}{
+		{
+			name:     "csv",
+			reader:   func() (Reader, error) { return NewCSV(strings.NewReader("name,age\na,1\n,\n"), nil) },
+			mode:     Atomic,
+			rows:     []insert{{[]string{"name", "age"}, []any{"a", int64(1)}}, {[]string{"name"}, []any{""}}},
+			flushed:  true,
+			inserted: 2,
+			errors:   []RowError{},
+		},
+		{
+			name: "json missing key and null",
+			reader: func() (Reader, error) {
+				return NewJSON(strings.NewReader(`[{"name":"a"},{"age":null}]`), []string{"name", "age"})
+			},
+			mode:     Atomic,
+			rows:     []insert{{[]string{"name"}, []any{"a"}}, {[]string{"age"}, []any{nil}}},
+			flushed:  true,
+			inserted: 2,
+			errors:   []RowError{},
+		},
+		{
+			name:   "atomic stops writing",
+			reader: func() (Reader, error) { return NewCSV(strings.NewReader("name,age\na,1\nb,x\nc,3\nd,y\n"), nil) },
+			mode:   Atomic,
+			rows:   []insert{{[]string{"name", "age"}, []any{"a", int64(1)}}},
+			errors: []RowError{
+				{Row: 2, Column: "age", Message: `"x" is not a valid integer`},
+				{Row: 4, Column: "age", Message: `"y" is not a valid integer`},
+			},
+		},
+		{
+			name: "skip",
+			reader: func() (Reader, error) {
+				return ndjson("{\"name\":\"a\"}\n{\"name\":true}\n{\"other\":1}\n{\"name\":\"b\"}\n")
+			},
+			mode:     Skip,
+			rows:     []insert{{[]string{"name"}, []any{"a"}}, {[]string{"name"}, []any{"b"}}},
+			flushed:  true,
+			inserted: 2,
+			errors: []RowError{
+				{Row: 2, Column: "name", Message: "must be a string"},
+				{Row: 3, Column: "other", Message: "unknown column"},
+			},
+		},
+		{
+			name:   "skip rejected by the store",
+			reader: func() (Reader, error) { return ndjson("{\"name\":\"a\"}\n{\"name\":\"dup\"}\n") },
+			mode:   Skip,
+			reject: func(columns []string, values []any) error {
+				if values[0] == "dup" {
+					return &store.RowError{Column: "name", Err: errors.New("duplicate key value violates unique constraint")}
+				}
+				return nil
+			},
+			rows:     []insert{{[]string{"name"}, []any{"a"}}},
+			flushed:  true,
+			inserted: 1,
+			errors:   []RowError{{Row: 2, Column: "name", Message: "duplicate key value violates unique constraint"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := test.reader()
+			if err != nil {
+				t.Fatal(err)
+			}
+			writer := &fakeWriter{reject: test.reject}
+
+			result, err := Run(context.Background(), reader, columns, test.mode, writer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(writer.rows, test.rows) {
+				t.Errorf("rows = %v, want %v", writer.rows, test.rows)
+			}
+			if writer.flushed != test.flushed {
+				t.Errorf("flushed = %v, want %v", writer.flushed, test.flushed)
+			}
+			if result.Inserted != test.inserted {
+				t.Errorf("inserted = %d, want %d", result.Inserted, test.inserted)
+			}
+			if result.Failed != len(test.errors) {
+				t.Errorf("failed = %d, want %d", result.Failed, len(test.errors))
+			}
+			if !reflect.DeepEqual(result.Errors, test.errors) {
+				t.Errorf("errors = %v, want %v", result.Errors, test.errors)
+			}
+		})
+	}
+}
+
+func TestRunRejectsColumns(t *testing.T) {
+	for _, header := range []string{"name,missing", "name,created_by"} {
+		reader, err := NewCSV(strings.NewReader(header+"\na,b\n"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writer := &fakeWriter{}
+		_, err = Run(context.Background(), reader, columns, Skip, writer)
+		var inputErr *InputError
+		if !errors.As(err, &inputErr) {
+			t.Errorf("%s: err = %v, want an InputError", header, err)
+		}
+		if len(writer.rows) > 0 {
+			t.Errorf("%s: rows were written", header)
+		}
+	}
+}
+
+func TestRunStoreErrorInAtomicMode(t *testing.T) {
+	reader, _ := ndjson("{\"name\":\"a\"}\n")
+	down := errors.New("connection reset")
+	writer := &fakeWriter{reject: func([]string, []any) error {
+		return &store.RowError{Err: down}
+	}}
+
+	if _, err := Run(context.Background(), reader, columns, Atomic, writer); !errors.Is(err, down) {
+		t.Fatalf("err = %v, want the store error", err)
+	}
+}
+
+func TestRunCapsErrors(t *testing.T) {
+	var body strings.Builder
+	body.WriteString("age\n")
+	for range MaxErrors + 5 {
+		body.WriteString("x\n")
+	}
+	body.WriteString("1\n")
+
+	reader, _ := NewCSV(strings.NewReader(body.String()), nil)
+	writer := &fakeWriter{}
+	result, err := Run(context.Background(), reader, columns, Skip, writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Failed != MaxErrors+5 {
+		t.Errorf("failed = %d, want %d", result.Failed, MaxErrors+5)
+	}
+	if len(result.Errors) != MaxErrors {
+		t.Errorf("kept %d errors, want %d", len(result.Errors), MaxErrors)
+	}
+	if result.Inserted != 1 {
+		t.Errorf("inserted = %d, want 1", result.Inserted)
+	}
+}
+
+func ndjson(body string) (Reader, error) {
+	return NewJSON(strings.NewReader(body), nil)
+}
@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Reader yields the records of an import body one at a time, keyed by column
+// name. Columns is known after the first call to Next.
+type Reader interface {
+	Next() (map[string]any, error)
+	Columns() []string
+}
+
+// csvReader reads a CSV body with a header row. Every field is a string.
+type csvReader struct {
+	reader  *csv.Reader
+	header  []string
+	columns []string
+}
+
+// NewCSV reads a CSV body with a header row. mapping renames header names to
+// column names, a header mapped to "" is ignored.
+func NewCSV(body io.Reader, mapping map[string]string) (Reader, error) {
+	reader := csv.NewReader(body)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, inputError("Failed to read CSV header: %v", err)
+	}
+
+	c := &csvReader{reader: reader, header: make([]string, len(header))}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if mapped, ok := mapping[name]; ok {
+			name = mapped
+		}
+		c.header[i] = name
+		if name != "" {
+			c.columns = append(c.columns, name)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *csvReader) Columns() []string {
+	return c.columns
+}
+
+func (c *csvReader) Next() (map[string]any, error) {
+	record, err := c.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	row := map[string]any{}
+	for i, value := range record {
+		if c.header[i] != "" {
+			row[c.header[i]] = value
+		}
+	}
+	return row, nil
+}
+
+// jsonReader reads either a JSON array of objects or a stream of objects, one
+// per line for NDJSON / JSON Lines.
+type jsonReader struct {
+	decoder *json.Decoder
+	array   bool
+	columns []string
+}
+
+// NewJSON reads a JSON array or a stream of JSON objects. columns lists the
+// columns of the records, nil takes the keys of the first record.
+func NewJSON(body io.Reader, columns []string) (Reader, error) {
+	buffered := bufio.NewReader(body)
+
+	j := &jsonReader{columns: columns}
+	for {
+		b, err := buffered.ReadByte()
+		if err != nil {
+			return nil, inputError("Empty body")
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		buffered.UnreadByte()
+		j.array = b == '['
+		break
+	}
+
+	j.decoder = json.NewDecoder(buffered)
+	j.decoder.UseNumber()
+
+	if j.array {
+		if _, err := j.decoder.Token(); err != nil {
+			return nil, inputError("Invalid JSON: %v", err)
+		}
+	}
+
+	return j, nil
+}
+
+func (j *jsonReader) Columns() []string {
+	return j.columns
+}
+
+func (j *jsonReader) Next() (map[string]any, error) {
+	if j.array && !j.decoder.More() {
+		return nil, io.EOF
+	}
+
+	var row map[string]any
+	if err := j.decoder.Decode(&row); err != nil {
+		return nil, err
+	}
+
+	// without an explicit column list the first record decides it
+	if j.columns == nil {
+		for name := range row {
+			j.columns = append(j.columns, name)
+		}
+		sort.Strings(j.columns)
+	}
+
+	return row, nil
+}
+
+// ParseMapping reads CSV header renames formatted as
+// "header:column,other:", where an empty column ignores the field.
+func ParseMapping(value string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if value == "" {
+		return mapping, nil
+	}
+
+	for pair := range strings.SplitSeq(value, ",") {
+		source, target, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, inputError("Invalid mapping %q, expected \"<header>:<column>\"", pair)
+		}
+		mapping[strings.TrimSpace(source)] = strings.TrimSpace(target)
+	}
+	return mapping, nil
+}
+
+// InputError is returned when the body or the options of an import can't be
+// used at all, as opposed to a single invalid row.
+type InputError struct {
+	message string
+}
+
+func (e *InputError) Error() string {
+	return e.message
+}
+
+func inputError(format string, args ...any) error {
+	return &InputError{message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestLoggerAddsContextIDs(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, "info", "json")
+
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-1")
+	ctx = WithRunID(ctx, "run-1")
+	logger.InfoContext(ctx, "hello")
+	logger.DebugContext(ctx, "hidden")
+
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("want a single JSON record, got %q: %v", out.String(), err)
+	}
+	if record["request_id"] != "req-1" || record["run_id"] != "run-1" {
+		t.Errorf("ids missing from %v", record)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"WARN":  slog.LevelWarn,
+		"error": slog.LevelError,
+		"":      slog.LevelInfo,
+	} {
+		if got := ParseLevel(name); got != want {
+			t.Errorf("ParseLevel(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestRequestIDIsEchoed(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = middleware.GetReqID(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(middleware.RequestIDHeader, "from-caller")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if seen != "from-caller" || w.Header().Get(middleware.RequestIDHeader) != "from-caller" {
+		t.Errorf("got id %q and header %q, want the id of the caller", seen, w.Header().Get(middleware.RequestIDHeader))
+	}
+}
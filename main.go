@@ -2,59 +2,174 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
-	"time"
+	"syscall"
 
+	"wolfscream/config"
 	"wolfscream/database"
 	"wolfscream/discord"
+	"wolfscream/handlers"
+	"wolfscream/health"
+	"wolfscream/logging"
+	"wolfscream/metrics"
+	"wolfscream/middlewares"
+	"wolfscream/retention"
 	"wolfscream/routes"
+	"wolfscream/scheduler"
+	"wolfscream/store"
+	"wolfscream/tablestream"
+	"wolfscream/tracing"
 	"wolfscream/websocket"
 	websocket_handlers "wolfscream/websocket/handlers"
 )
 
 func main() {
-	websocket.InitHub()
-	websocket_handlers.InitHandlers()
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		// there is no logger yet, the problems are for a human to read
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	// the log package writes through slog from here on
+	slog.SetDefault(logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		Stdout:       cfg.Tracing.Stdout,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
+
+	db, err := database.Open(context.Background(), cfg.Database.DSN())
+	if err != nil {
+		fatal("failed to connect to the database", err)
+	}
+
+	if len(args) > 0 && args[0] == "migrate" {
+		runMigrate(db, args[1:])
+		return
+	}
+
+	if cfg.Database.MigrateOnStart {
+		migrateUp(db)
+	}
+
+	// Discord connects on first use and is optional
+	bot := discord.New(cfg.Discord.Token)
+	if cfg.Discord.Token == "" {
+		slog.Warn("DISCORD_BOT_TOKEN is not set, Discord is disabled")
+	}
+
+	var backplane websocket.Backplane
+	if cfg.WebSocket.Backplane == config.BackplanePostgres {
+		backplane = websocket.NewPostgresBackplane(db, cfg.Database.DSN())
+	}
+
+	if err := websocket.InitHub(backplane); err != nil {
+		fatal("failed to start the WebSocket hub", err)
+	}
+
+	instruments := metrics.New()
+	instruments.RegisterDB(db)
+	instruments.RegisterHub(websocket.GetHub())
+	bot.Observe(instruments.ObserveSend)
 
-	r := routes.NewRouter()
+	jobs := scheduler.New(cfg.Scheduler.JobTimeout)
+	websocket_handlers.InitHandlers(db, jobs)
+
+	tableStream, err := tablestream.Start(context.Background(), db, cfg.Database.DSN())
+	if err != nil {
+		fatal("failed to start the table stream", err)
+	}
+
+	middlewares.AllowedOrigins = cfg.CORSOrigins
+	repositories := store.New(db)
+
+	pruner := retention.Start(context.Background(), repositories.Logs, repositories.DB, retention.Policy{
+		"INFO":  cfg.LogRetention.Info,
+		"WARN":  cfg.LogRetention.Warn,
+		"ERROR": cfg.LogRetention.Error,
+	}, cfg.LogRetention.Interval)
+
+	h := handlers.New(repositories, jobs, bot)
+	h.OnScheduledMessageEvent(instruments.ObserveScheduledMessageEvent)
+	r := routes.NewRouter(h, cfg.RequestTimeout, tracing.Middleware, instruments.Middleware)
 
 	r.Get("/ws", websocket.HandleWebSocket)
 
+	probes := health.New(db, bot, jobs)
+	r.Get("/healthz", probes.Healthz)
+	r.Get("/readyz", probes.Readyz)
+	r.Get("/version", probes.Version)
+
+	r.Handle("/metrics", instruments.Handler())
+
 	srv := &http.Server {
-		Addr: ":8080",
+		Addr: cfg.Addr,
 		Handler: r,
 	}
 	
 
 	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("ListenAndServe error: %v", err)
+			fatal("failed to serve", err)
 		}
 	}()
 	
 	<-stop
-	log.Println("Shutting down server...")
+	slog.Info("shutting down")
 
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
+	// the jobs, streams and connections are still closed when requests
+	// outlive the timeout
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server Shutdown Failed:%+v", err)
+		slog.Error("failed to shut down the server", "error", err)
+		srv.Close()
+	}
+
+	// running jobs are canceled and must return before the pool closes
+	if err := jobs.Shutdown(ctx); err != nil {
+		slog.Warn("scheduled jobs did not finish", "error", err)
 	}
 
-	discord.DiscordBot.Close()
+	tableStream.Close()
+	pruner.Close()
 
-	if err := database.DB.Close(); err != nil {
-		log.Printf("Error closing DB: %v", err)
+	if backplane != nil {
+		backplane.Close()
 	}
 
-	log.Println("Server exited properly")
+	if err := bot.Close(); err != nil {
+		slog.Error("failed to close the Discord session", "error", err)
+	}
+
+	if err := db.Close(); err != nil {
+		slog.Error("failed to close the database", "error", err)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush the traces", "error", err)
+	}
+
+	slog.Info("server exited")
 	
 }
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,187 @@
+// Package metrics exposes Prometheus metrics on /metrics. Nothing is counted
+// by hand in the handlers: the HTTP metrics come from a middleware, the run
+// metrics from the scheduled message events, the delivery metrics from the
+// notifier send hook and the rest from collectors reading the hub and the
+// connection pool.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wolfscream/models"
+	"wolfscream/notifier"
+	"wolfscream/websocket"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wolfscream"
+
+// rowBuckets fit tables from a handful of rows to a few hundred thousand.
+var rowBuckets = prometheus.ExponentialBuckets(1, 4, 10)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	runs        *prometheus.CounterVec
+	rowsScanned *prometheus.HistogramVec
+	rowsMatched *prometheus.HistogramVec
+
+	sendDuration *prometheus.HistogramVec
+	sendFailures *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "scheduled_message_runs_total",
+			Help:      "Scheduled message runs by name and outcome.",
+		}, []string{"scheduled_message", "outcome"}),
+		rowsScanned: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "scheduled_message_rows_scanned",
+			Help:      "Rows scanned by a scheduled message run.",
+			Buckets:   rowBuckets,
+		}, []string{"scheduled_message"}),
+		rowsMatched: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "scheduled_message_rows_matched",
+			Help:      "Rows matching the rule in a scheduled message run.",
+			Buckets:   rowBuckets,
+		}, []string{"scheduled_message"}),
+
+		sendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "notifier_send_duration_seconds",
+			Help:      "Latency of message deliveries by platform.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"platform"}),
+		sendFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "notifier_send_failures_total",
+			Help:      "Failed message deliveries by platform and reason.",
+		}, []string{"platform", "reason"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.runs, m.rowsScanned, m.rowsMatched,
+		m.sendDuration, m.sendFailures,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB adds the statistics of the connection pool.
+func (m *Metrics) RegisterDB(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// RegisterHub adds the number of WebSocket clients and subscriptions of hub,
+// read when the metrics are scraped.
+func (m *Metrics) RegisterHub(hub *websocket.Hub) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "websocket_clients",
+			Help:      "Connected WebSocket clients.",
+		}, func() float64 {
+			clients, _ := hub.Stats()
+			return float64(clients)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "websocket_subscriptions",
+			Help:      "Topic subscriptions of the connected WebSocket clients.",
+		}, func() float64 {
+			_, subscriptions := hub.Stats()
+			return float64(subscriptions)
+		}),
+	)
+}
+
+// Middleware counts and times the requests. Routes are labelled with their
+// chi pattern, such as /api/v1/table/{table-name}, so the label set stays
+// bounded; requests no route matched share the "unmatched" label.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		m.httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveScheduledMessageEvent records the outcome of the finished runs. It
+// is meant to be hooked to the scheduled message events.
+func (m *Metrics) ObserveScheduledMessageEvent(name string, event models.ScheduledMessageEvent) {
+	if event.Event != "run_finished" {
+		return
+	}
+
+	m.runs.WithLabelValues(name, event.Status).Inc()
+	if event.RowsScanned != nil {
+		m.rowsScanned.WithLabelValues(name).Observe(float64(*event.RowsScanned))
+	}
+	if event.RowsMatched != nil {
+		m.rowsMatched.WithLabelValues(name).Observe(float64(*event.RowsMatched))
+	}
+}
+
+// ObserveSend records a delivery attempt. It is a notifier.SendObserver.
+func (m *Metrics) ObserveSend(platform string, took time.Duration, err error) {
+	switch {
+	case err == nil:
+		m.sendDuration.WithLabelValues(platform).Observe(took.Seconds())
+	case errors.Is(err, notifier.ErrNotConfigured), errors.Is(err, notifier.ErrUnavailable):
+		m.sendFailures.WithLabelValues(platform, "unavailable").Inc()
+	default:
+		m.sendDuration.WithLabelValues(platform).Observe(took.Seconds())
+		m.sendFailures.WithLabelValues(platform, "error").Inc()
+	}
+}
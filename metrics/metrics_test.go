@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wolfscream/models"
+	"wolfscream/notifier"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareLabelsRoutePattern(t *testing.T) {
+	m := New()
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/table/{table-name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/table/users", "/table/orders", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/table/{table-name}", "404")); got != 2 {
+		t.Errorf("got %v requests on the pattern, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("got %v unmatched requests, want 1", got)
+	}
+}
+
+func TestObserveScheduledMessageEvent(t *testing.T) {
+	m := New()
+	scanned, matched := 40, 3
+
+	m.ObserveScheduledMessageEvent("daily", models.ScheduledMessageEvent{Event: "run_started"})
+	m.ObserveScheduledMessageEvent("daily", models.ScheduledMessageEvent{
+		Event:       "run_finished",
+		Status:      "success",
+		RowsScanned: &scanned,
+		RowsMatched: &matched,
+	})
+	m.ObserveScheduledMessageEvent("daily", models.ScheduledMessageEvent{Event: "run_finished", Status: "failed"})
+
+	if got := testutil.ToFloat64(m.runs.WithLabelValues("daily", "success")); got != 1 {
+		t.Errorf("got %v successful runs, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.runs.WithLabelValues("daily", "failed")); got != 1 {
+		t.Errorf("got %v failed runs, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.rowsScanned); got != 1 {
+		t.Errorf("got %d rows scanned series, want 1", got)
+	}
+}
+
+func TestObserveSend(t *testing.T) {
+	m := New()
+
+	m.ObserveSend("discord", time.Millisecond, nil)
+	m.ObserveSend("discord", 0, fmt.Errorf("send: %w", notifier.ErrUnavailable))
+	m.ObserveSend("discord", time.Millisecond, errors.New("rate limited"))
+
+	if got := testutil.ToFloat64(m.sendFailures.WithLabelValues("discord", "unavailable")); got != 1 {
+		t.Errorf("got %v unavailable failures, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.sendFailures.WithLabelValues("discord", "error")); got != 1 {
+		t.Errorf("got %v errors, want 1", got)
+	}
+}
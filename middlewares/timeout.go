@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// untimedKey holds the context of the request before Timeout, for NoTimeout.
+type untimedKey struct{}
+
+// Timeout cancels the context of a request after d, which stops the queries
+// run for it. Unlike chi's middleware.Timeout it doesn't write a response,
+// so handlers that already started streaming aren't cut off mid-body. Zero
+// disables the timeout.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			untimed := r.Context()
+			ctx, cancel := context.WithTimeout(context.WithValue(untimed, untimedKey{}, untimed), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// NoTimeout lifts the deadline set by Timeout for routes that legitimately
+// run long, such as exports, imports and index builds. The request is still
+// canceled when the client goes away. It must come before the middlewares of
+// the route that add values to the context.
+func NoTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if untimed, ok := r.Context().Value(untimedKey{}).(context.Context); ok {
+			r = r.WithContext(untimed)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
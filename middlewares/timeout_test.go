@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNoTimeoutLiftsTheDeadline(t *testing.T) {
+	var timed, untimed bool
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, timed = r.Context().Deadline()
+		NoTimeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, untimed = r.Context().Deadline()
+		})).ServeHTTP(w, r)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !timed {
+		t.Error("Timeout didn't set a deadline")
+	}
+	if untimed {
+		t.Error("NoTimeout kept the deadline")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"wolfscream/migrations"
+)
+
+// runMigrate handles `wolfscream [flags] migrate [up|status]`.
+func runMigrate(db *sql.DB, args []string) {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		migrateUp(db)
+	case "status":
+		statuses, err := migrations.List(context.Background(), db)
+		if err != nil {
+			fatal("failed to list migrations", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		w.Flush()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown migrate command: %s (expected up or status)\n", command)
+		os.Exit(1)
+	}
+}
+
+func migrateUp(db *sql.DB) {
+	applied, err := migrations.Up(context.Background(), db)
+	if err != nil {
+		fatal("failed to apply migrations", err)
+	}
+	for _, migration := range applied {
+		slog.Info("applied migration", "version", migration.Version, "name", migration.Name)
+	}
+}
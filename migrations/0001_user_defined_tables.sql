@@ -0,0 +1,66 @@
+-- Metadata of the tables created through the API.
+--
+-- Every statement is guarded so databases created from the old schema dump
+-- are brought up to date instead of failing.
+
+CREATE TABLE IF NOT EXISTS user_defined_table (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_defined_column (
+    id SERIAL PRIMARY KEY,
+    user_defined_table_id INTEGER NOT NULL REFERENCES user_defined_table(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    length INTEGER,
+    is_nullable BOOLEAN NOT NULL DEFAULT FALSE,
+    default_value TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE user_defined_column
+    ADD COLUMN IF NOT EXISTS "precision" INTEGER,
+    ADD COLUMN IF NOT EXISTS scale INTEGER,
+    ADD COLUMN IF NOT EXISTS enum_type VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS enum_values TEXT[],
+    ADD COLUMN IF NOT EXISTS is_unique BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS check_expression TEXT,
+    ADD COLUMN IF NOT EXISTS references_table VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS on_delete VARCHAR(16);
+
+CREATE TABLE IF NOT EXISTS user_defined_index (
+    id SERIAL PRIMARY KEY,
+    user_defined_table_id INTEGER NOT NULL REFERENCES user_defined_table(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    method VARCHAR(16) NOT NULL,
+    is_unique BOOLEAN NOT NULL DEFAULT FALSE,
+    columns TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Installed on every user defined table by CreateTable, publishes row changes
+-- to tablestream.
+CREATE OR REPLACE FUNCTION notify_user_defined_table_change() RETURNS trigger AS $$
+DECLARE
+    payload TEXT;
+    row_data JSON;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        row_data := row_to_json(OLD);
+    ELSE
+        row_data := row_to_json(NEW);
+    END IF;
+
+    payload := json_build_object('table', TG_TABLE_NAME, 'event', lower(TG_OP), 'row', row_data)::text;
+
+    IF octet_length(payload) > 7900 THEN
+        payload := json_build_object('table', TG_TABLE_NAME, 'event', lower(TG_OP), 'row', NULL, 'truncated', true)::text;
+    END IF;
+
+    PERFORM pg_notify('user_defined_table_change', payload);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,131 @@
+-- Scheduled messages, their schedules, platforms and history.
+
+DO $$
+BEGIN
+    CREATE TYPE scheduled_message_schedule_type AS ENUM ('interval', 'cron');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+DO $$
+BEGIN
+    CREATE TYPE scheduled_message_interval_unit AS ENUM ('m', 's');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+DO $$
+BEGIN
+    CREATE TYPE scheduled_message_state AS ENUM ('started', 'stopped');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+DO $$
+BEGIN
+    CREATE TYPE scheduled_message_log_level AS ENUM ('INFO', 'WARN', 'ERROR');
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+CREATE TABLE IF NOT EXISTS communication_platform (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE communication_platform ADD COLUMN IF NOT EXISTS image_url TEXT NOT NULL DEFAULT '';
+
+INSERT INTO communication_platform (name, description) VALUES ('discord', 'Discord channel messages')
+ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS scheduled_message (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    user_defined_table_id INTEGER NOT NULL REFERENCES user_defined_table(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    rule TEXT NOT NULL,
+    schedule_type scheduled_message_schedule_type NOT NULL,
+    communication_platform_id INTEGER NOT NULL REFERENCES communication_platform(id) ON DELETE CASCADE,
+    description TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_message_interval (
+    id SERIAL PRIMARY KEY,
+    scheduled_message_id INTEGER NOT NULL REFERENCES scheduled_message(id) ON DELETE CASCADE,
+    value INTEGER NOT NULL,
+    unit scheduled_message_interval_unit NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_message_cron (
+    id SERIAL PRIMARY KEY,
+    scheduled_message_id INTEGER NOT NULL REFERENCES scheduled_message(id) ON DELETE CASCADE,
+    minute VARCHAR(8),
+    hour VARCHAR(8),
+    day_of_month VARCHAR(8),
+    month VARCHAR(8),
+    day_of_week VARCHAR(8),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_message_discord_config (
+    id SERIAL PRIMARY KEY,
+    scheduled_message_id INTEGER NOT NULL UNIQUE REFERENCES scheduled_message(id) ON DELETE CASCADE,
+    channel_id VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_message_rule (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    description TEXT,
+    rule TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- id is the id of the scheduler entry running the message.
+CREATE TABLE IF NOT EXISTS running_scheduled_message (
+    id INTEGER PRIMARY KEY,
+    scheduled_message_id INTEGER NOT NULL UNIQUE REFERENCES scheduled_message(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_message_execution_history (
+    id SERIAL PRIMARY KEY,
+    scheduled_message_id INTEGER NOT NULL REFERENCES scheduled_message(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_message_state_history (
+    id SERIAL PRIMARY KEY,
+    scheduled_message_id INTEGER NOT NULL REFERENCES scheduled_message(id) ON DELETE CASCADE,
+    state scheduled_message_state NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_message_log (
+    id SERIAL PRIMARY KEY,
+    scheduled_message_id INTEGER NOT NULL REFERENCES scheduled_message(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    level scheduled_message_log_level NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- the old schema dump lacked these columns
+ALTER TABLE scheduled_message_execution_history ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'success';
+ALTER TABLE scheduled_message_execution_history ALTER COLUMN status DROP DEFAULT;
+
+-- the old state history and log rows have nothing tying them to a scheduled
+-- message, they can't be backfilled and are deleted before the column is
+-- made NOT NULL
+ALTER TABLE scheduled_message_state_history ADD COLUMN IF NOT EXISTS scheduled_message_id INTEGER REFERENCES scheduled_message(id) ON DELETE CASCADE;
+DELETE FROM scheduled_message_state_history WHERE scheduled_message_id IS NULL;
+ALTER TABLE scheduled_message_state_history ALTER COLUMN scheduled_message_id SET NOT NULL;
+
+ALTER TABLE scheduled_message_log ADD COLUMN IF NOT EXISTS scheduled_message_id INTEGER REFERENCES scheduled_message(id) ON DELETE CASCADE;
+DELETE FROM scheduled_message_log WHERE scheduled_message_id IS NULL;
+ALTER TABLE scheduled_message_log ALTER COLUMN scheduled_message_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS scheduled_message_execution_history_scheduled_message_id_idx ON scheduled_message_execution_history (scheduled_message_id);
+CREATE INDEX IF NOT EXISTS scheduled_message_state_history_scheduled_message_id_idx ON scheduled_message_state_history (scheduled_message_id, created_at);
+CREATE INDEX IF NOT EXISTS scheduled_message_log_scheduled_message_id_idx ON scheduled_message_log (scheduled_message_id, created_at);
@@ -0,0 +1,4 @@
+-- the scheduled message run that wrote the log, NULL for logs written outside of a run
+ALTER TABLE scheduled_message_log ADD COLUMN IF NOT EXISTS run_id UUID;
+
+CREATE INDEX IF NOT EXISTS scheduled_message_log_run_id_idx ON scheduled_message_log (run_id);
@@ -0,0 +1,2 @@
+-- the retention job deletes the old logs of each level
+CREATE INDEX IF NOT EXISTS scheduled_message_log_level_created_at_idx ON scheduled_message_log (level, created_at);
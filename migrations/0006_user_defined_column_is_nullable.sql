@@ -0,0 +1,11 @@
+-- columns registered before the constraints were tracked kept the default of
+-- is_nullable, FALSE, even though they accept NULL. Take the nullability of
+-- the catalog instead so drift doesn't report them.
+UPDATE user_defined_column udc
+SET is_nullable = c.is_nullable = 'YES'
+FROM user_defined_table udt, information_schema.columns c
+WHERE udc.user_defined_table_id = udt.id
+    AND c.table_schema = current_schema()
+    AND c.table_name = udt.name
+    AND c.column_name = udc.name
+    AND udc.is_nullable <> (c.is_nullable = 'YES');
@@ -0,0 +1,45 @@
+package migrations
+
+import "testing"
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %s has version %d, want %d: versions must not skip", migration.Name, migration.Version, i+1)
+		}
+		if migration.SQL == "" {
+			t.Errorf("migration %s is empty", migration.Name)
+		}
+	}
+}
+
+func TestParseFileName(t *testing.T) {
+	tests := []struct {
+		file    string
+		version int
+		name    string
+		ok      bool
+	}{
+		{"0001_initial.sql", 1, "initial", true},
+		{"12_add_logs.sql", 12, "add_logs", true},
+		{"initial.sql", 0, "", false},
+		{"0001_.sql", 0, "", false},
+		{"0000_zero.sql", 0, "", false},
+		{"0001_initial.txt", 0, "", false},
+	}
+
+	for _, test := range tests {
+		version, name, err := parseFileName(test.file)
+		if (err == nil) != test.ok || version != test.version || name != test.name {
+			t.Errorf("parseFileName(%q) = %d, %q, %v", test.file, version, name, err)
+		}
+	}
+}
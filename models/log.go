@@ -7,5 +7,7 @@ type Log struct {
 	Text       string    `json:"text"`
 	Level      string    `json:"level"`
 	CreatedAt  time.Time `json:"created_at"`
+	// RunId is the scheduled message run that wrote the log, if any.
+	RunId      *string   `json:"run_id,omitempty"`
 	
 }
\ No newline at end of file
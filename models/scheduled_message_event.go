@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ScheduledMessageEvent is published on the scheduled-message:{name} topic.
+type ScheduledMessageEvent struct {
+	Event       string     `json:"event"`
+	State       string     `json:"state,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	RunId       string     `json:"run_id,omitempty"`
+	RowsScanned *int       `json:"rows_scanned,omitempty"`
+	RowsMatched *int       `json:"rows_matched,omitempty"`
+	Log         *Log       `json:"log,omitempty"`
+	PrevRun     *time.Time `json:"prev_run,omitempty"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
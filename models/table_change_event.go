@@ -0,0 +1,16 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TableChangeEvent is published on the table:{name} topic whenever a row of a
+// user defined table is inserted, updated or deleted.
+type TableChangeEvent struct {
+	Event     string          `json:"event"`
+	Table     string          `json:"table"`
+	Row       json.RawMessage `json:"row"`
+	Truncated bool            `json:"truncated,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
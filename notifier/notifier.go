@@ -0,0 +1,49 @@
+// Package notifier describes the platforms scheduled messages are sent to.
+// Platforms are optional: one that isn't configured or can't connect leaves
+// the rest of the server running.
+package notifier
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotConfigured is returned when a platform is used without
+	// credentials.
+	ErrNotConfigured = errors.New("platform is not configured")
+	// ErrUnavailable wraps the error of a failed connection attempt.
+	ErrUnavailable = errors.New("platform is unavailable")
+)
+
+const (
+	// StateNotConfigured means the platform has no credentials.
+	StateNotConfigured = "not_configured"
+	// StateIdle means the platform connects on first use.
+	StateIdle      = "idle"
+	StateConnected = "connected"
+	// StateUnavailable means the last connection attempt failed, the next
+	// use retries it.
+	StateUnavailable = "unavailable"
+	// StateDisconnected means the platform lost its connection and is
+	// reconnecting.
+	StateDisconnected = "disconnected"
+	// StateUnsupported is reported for platforms the server has no client
+	// for.
+	StateUnsupported = "unsupported"
+)
+
+// Status is the connection status of a platform.
+type Status struct {
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+// Platform is implemented by the client of every platform.
+type Platform interface {
+	Status() Status
+}
+
+// SendObserver is told about every delivery attempt, with how long it took
+// and its error. Metrics hook in here.
+type SendObserver func(platform string, took time.Duration, err error)
@@ -0,0 +1,86 @@
+// Package retention prunes the logs of the scheduled messages in the
+// background, keeping the logs of each level for its own age.
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"wolfscream/store"
+)
+
+// Policy maps a log level to how long its logs are kept. A level missing or
+// mapped to 0 is kept forever.
+type Policy map[string]time.Duration
+
+// Pruner deletes the expired logs every interval until it is closed.
+type Pruner struct {
+	logs   store.LogStore
+	db     store.DBTX
+	policy Policy
+	now    func() time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start prunes the logs right away and then every interval.
+func Start(ctx context.Context, logs store.LogStore, db store.DBTX, policy Policy, interval time.Duration) *Pruner {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &Pruner{
+		logs:   logs,
+		db:     db,
+		policy: policy,
+		now:    time.Now,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			p.prune(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return p
+}
+
+// Close stops the pruner and waits for a prune in progress to return.
+func (p *Pruner) Close() {
+	p.cancel()
+	<-p.done
+}
+
+// prune deletes the expired logs of every level of the policy. A failing
+// level doesn't stop the others, it is retried on the next prune.
+func (p *Pruner) prune(ctx context.Context) {
+	for _, level := range store.LogLevels {
+		age := p.policy[level]
+		if age <= 0 {
+			continue
+		}
+
+		deleted, err := p.logs.Prune(ctx, p.db, level, p.now().Add(-age))
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "failed to prune scheduled message logs", "level", level, "error", err)
+			}
+			continue
+		}
+		if deleted > 0 {
+			slog.InfoContext(ctx, "pruned scheduled message logs", "level", level, "deleted", deleted, "max_age", age)
+		}
+	}
+}
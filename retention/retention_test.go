@@ -0,0 +1,63 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"wolfscream/store"
+)
+
+type fakeLogs struct {
+	store.LogStore
+	cutoffs map[string]time.Time
+	fail    string
+}
+
+func (f *fakeLogs) Prune(ctx context.Context, db store.DBTX, level string, cutoff time.Time) (int64, error) {
+	if level == f.fail {
+		return 0, errors.New("down")
+	}
+	f.cutoffs[level] = cutoff
+	return 1, nil
+}
+
+func TestPruneUsesTheAgeOfEachLevel(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	logs := &fakeLogs{cutoffs: map[string]time.Time{}, fail: "WARN"}
+
+	p := &Pruner{
+		logs:   logs,
+		policy: Policy{"INFO": time.Hour, "WARN": 2 * time.Hour, "ERROR": 0},
+		now:    func() time.Time { return now },
+	}
+	p.prune(context.Background())
+
+	if got := logs.cutoffs["INFO"]; !got.Equal(now.Add(-time.Hour)) {
+		t.Errorf("INFO cutoff = %s, want an hour ago", got)
+	}
+	if _, ok := logs.cutoffs["ERROR"]; ok {
+		t.Error("ERROR logs were pruned, an age of 0 keeps them")
+	}
+}
+
+func TestCloseStopsThePruner(t *testing.T) {
+	logs := &fakeLogs{cutoffs: map[string]time.Time{}}
+	p := Start(context.Background(), logs, nil, Policy{"INFO": time.Hour}, time.Hour)
+
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close didn't return")
+	}
+	if _, ok := logs.cutoffs["INFO"]; !ok {
+		t.Error("the logs weren't pruned on start")
+	}
+}
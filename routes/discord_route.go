@@ -7,12 +7,12 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-func DiscordRoutes() chi.Router {
+func DiscordRoutes(h *handlers.Handler) chi.Router {
 	router := chi.NewRouter()
 
 
-	router.With(middlewares.AuthMiddleware).Get("/guild/{guildId}/channel", handlers.ListDiscordChannels)
-	router.With(middlewares.AuthMiddleware).Get("/guild", handlers.ListDiscordGuilds)
+	router.With(middlewares.AuthMiddleware).Get("/guild/{guildId}/channel", h.ListDiscordChannels)
+	router.With(middlewares.AuthMiddleware).Get("/guild", h.ListDiscordGuilds)
 
 
 	return router
@@ -7,11 +7,11 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-func PlatformRoutes() chi.Router {
+func PlatformRoutes(h *handlers.Handler) chi.Router {
 	router := chi.NewRouter()
 
 
-	router.With(middlewares.AuthMiddleware).Get("/", handlers.ListPlatforms)
+	router.With(middlewares.AuthMiddleware).Get("/", h.ListPlatforms)
 
 	
 
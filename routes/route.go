@@ -1,26 +1,41 @@
 package routes
 
 import (
+	"net/http"
+	"time"
+
+	"wolfscream/handlers"
+	"wolfscream/logging"
 	"wolfscream/middlewares"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func NewRouter() *chi.Mux {
+// NewRouter mounts the API. requestTimeout bounds every API request but the
+// long running ones marked with middlewares.NoTimeout, the WebSocket
+// endpoint mounted by main is left alone. instrument wraps every
+// request ahead of the other middlewares, so it also sees the requests they
+// reject.
+func NewRouter(h *handlers.Handler, requestTimeout time.Duration, instrument ...func(http.Handler) http.Handler) *chi.Mux {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Logger)
+	r.Use(instrument...)
+	r.Use(logging.RequestID)
+	r.Use(logging.Middleware)
 	r.Use(middlewares.CORS())
 	r.Use(middleware.Recoverer)
 
 	r.Route("/api/v1", func(router chi.Router) {
-		router.Mount("/table", SchemaRoutes())
-		router.Mount("/message-template", TemplateRoutes())
-		router.Mount("/scheduled-message", ScheduledMessageRoutes())
-		router.Mount("/discord", DiscordRoutes())
-		router.Mount("/rule", RuleRoutes())
-		router.Mount("/platform", PlatformRoutes())
+		router.Use(middlewares.Timeout(requestTimeout))
+
+		router.Mount("/table", SchemaRoutes(h))
+		router.Mount("/message-template", TemplateRoutes(h))
+		router.Mount("/scheduled-message", ScheduledMessageRoutes(h))
+		router.Mount("/discord", DiscordRoutes(h))
+		router.Mount("/rule", RuleRoutes(h))
+		router.Mount("/platform", PlatformRoutes(h))
+		router.Mount("/schema", SchemaAdminRoutes(h))
 	})
 
 	return r
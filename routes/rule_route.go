@@ -7,11 +7,11 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-func RuleRoutes() chi.Router {
+func RuleRoutes(h *handlers.Handler) chi.Router {
 	router := chi.NewRouter()
 
-	router.With(middlewares.AuthMiddleware).Get("/", handlers.ListRules)
-	router.With(middlewares.AuthMiddleware).Post("/", handlers.AddRule)
+	router.With(middlewares.AuthMiddleware).Get("/", h.ListRules)
+	router.With(middlewares.AuthMiddleware).Post("/", h.AddRule)
 
 	return router
 
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"wolfscream/handlers"
+	"wolfscream/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func SchemaAdminRoutes(h *handlers.Handler) chi.Router {
+	router := chi.NewRouter()
+
+	router.With(middlewares.AuthMiddleware).Get("/drift", h.GetDrift)
+	router.With(middlewares.AuthMiddleware).Post("/drift/repair", h.RepairDrift)
+
+	router.With(middlewares.NoTimeout, middlewares.AuthMiddleware).Post("/apply", h.ApplySchema)
+
+	return router
+}
@@ -7,22 +7,34 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-func SchemaRoutes() chi.Router {
+func SchemaRoutes(h *handlers.Handler) chi.Router {
 	router := chi.NewRouter()
 
-	router.With(middlewares.AuthMiddleware).Post("/", handlers.CreateTable)
-	router.With(middlewares.AuthMiddleware).Get("/", handlers.ListTables)
-	router.With(middlewares.AuthMiddleware).Put("/{table-name}", handlers.UpdateTable)
-	router.With(middlewares.AuthMiddleware).Delete("/{table-name}", handlers.DropTable)
-
-	router.With(middlewares.AuthMiddleware).Post("/{table-name}/column", handlers.AddColumn)
-	router.With(middlewares.AuthMiddleware).Get("/{table-name}/column", handlers.ListColumns)
-	router.With(middlewares.AuthMiddleware).Delete("/{table-name}/column/{column}", handlers.DeleteColumn)
-	router.With(middlewares.AuthMiddleware).Put("/{table-name}/column/{column}", handlers.UpdateColumn)
-
-	router.With(middlewares.AuthMiddleware).Get("/{table-name}/data", handlers.GetData)
-	router.With(middlewares.AuthMiddleware).Post("/{table-name}/data", handlers.InsertData)
-	router.With(middlewares.AuthMiddleware).Delete("/{table-name}/data/{columnId}", handlers.DeleteData)
+	router.With(middlewares.AuthMiddleware).Post("/", h.CreateTable)
+	router.With(middlewares.AuthMiddleware).Get("/", h.ListTables)
+	router.With(middlewares.AuthMiddleware).Get("/{table-name}", h.DescribeTable)
+	router.With(middlewares.AuthMiddleware).Get("/{table-name}/schema", h.ExportTableSchema)
+	router.With(middlewares.AuthMiddleware).Put("/{table-name}", h.UpdateTable)
+	router.With(middlewares.AuthMiddleware).Delete("/{table-name}", h.DropTable)
+
+	router.With(middlewares.AuthMiddleware).Post("/{table-name}/column", h.AddColumn)
+	router.With(middlewares.AuthMiddleware).Get("/{table-name}/column", h.ListColumns)
+	router.With(middlewares.AuthMiddleware).Delete("/{table-name}/column/{column}", h.DeleteColumn)
+	router.With(middlewares.AuthMiddleware).Put("/{table-name}/column/{column}", h.UpdateColumn)
+
+	router.With(middlewares.NoTimeout, middlewares.AuthMiddleware).Post("/{table-name}/index", h.CreateIndex)
+	router.With(middlewares.AuthMiddleware).Get("/{table-name}/index", h.ListIndexes)
+	router.With(middlewares.NoTimeout, middlewares.AuthMiddleware).Delete("/{table-name}/index/{index}", h.DropIndex)
+
+	router.With(middlewares.AuthMiddleware).Get("/{table-name}/data", h.GetData)
+	router.With(middlewares.AuthMiddleware).Post("/{table-name}/data", h.InsertData)
+	router.With(middlewares.AuthMiddleware).Get("/{table-name}/data/{id}", h.GetRow)
+	router.With(middlewares.AuthMiddleware).Patch("/{table-name}/data/{id}", h.UpdateRow)
+	router.With(middlewares.AuthMiddleware).Put("/{table-name}/data/{id}", h.UpdateRow)
+	router.With(middlewares.AuthMiddleware).Delete("/{table-name}/data/{id}", h.DeleteData)
+
+	router.With(middlewares.NoTimeout, middlewares.AuthMiddleware).Post("/{table-name}/import", h.ImportData)
+	router.With(middlewares.NoTimeout, middlewares.AuthMiddleware).Get("/{table-name}/export", h.ExportData)
 
 	return router
 
@@ -7,12 +7,12 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-func TemplateRoutes() chi.Router {
+func TemplateRoutes(h *handlers.Handler) chi.Router {
 	router := chi.NewRouter()
 
-	router.With(middlewares.AuthMiddleware).Post("/", handlers.AddTemplate)
-	router.With(middlewares.AuthMiddleware).Get("/", handlers.ListMessageTemplates)
-	router.With(middlewares.AuthMiddleware).Delete("/{messageTemplateId}", handlers.DeleteMessageTemplate)
+	router.With(middlewares.AuthMiddleware).Post("/", h.AddTemplate)
+	router.With(middlewares.AuthMiddleware).Get("/", h.ListMessageTemplates)
+	router.With(middlewares.AuthMiddleware).Delete("/{messageTemplateId}", h.DeleteMessageTemplate)
 
 	return router
 	
@@ -0,0 +1,79 @@
+// Package rule implements the expression language shared by scheduled message
+// rules and data queries.
+//
+// An expression is a list of conditions separated by ";", every condition has
+// the form "<column> <operator> <value>" and all of them must hold:
+//
+//	severity == high; status != closed
+package rule
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	Equal    = "=="
+	NotEqual = "!="
+)
+
+type Condition struct {
+	Column   string
+	Operator string
+	Value    string
+}
+
+// Parse splits an expression into its conditions. An empty expression has no
+// conditions and matches every row.
+func Parse(expression string) ([]Condition, error) {
+	conditions := []Condition{}
+
+	for part := range strings.SplitSeq(expression, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		parts := strings.SplitN(part, " ", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid condition %q, expected \"<column> <operator> <value>\"", part)
+		}
+
+		condition := Condition{Column: parts[0], Operator: parts[1], Value: parts[2]}
+		switch condition.Operator {
+		case Equal, NotEqual:
+		default:
+			return nil, fmt.Errorf("unknown operator %q in condition %q", condition.Operator, part)
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
+
+// Match reports whether the row satisfies every condition. Values are
+// compared by their text representation, a missing column never matches.
+func Match(conditions []Condition, row map[string]any) bool {
+	for _, condition := range conditions {
+		v, ok := row[condition.Column]
+		if !ok {
+			return false
+		}
+
+		switch condition.Operator {
+		case Equal:
+			if fmt.Sprintf("%v", v) != condition.Value {
+				return false
+			}
+		case NotEqual:
+			if fmt.Sprintf("%v", v) == condition.Value {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,57 @@
+package rule
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	conditions, err := Parse(" severity == high; ; agent_id != 001 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Condition{
+		{Column: "severity", Operator: Equal, Value: "high"},
+		{Column: "agent_id", Operator: NotEqual, Value: "001"},
+	}
+	if len(conditions) != len(want) {
+		t.Fatalf("got %d conditions, want %d", len(conditions), len(want))
+	}
+	for i := range want {
+		if conditions[i] != want[i] {
+			t.Errorf("condition %d = %+v, want %+v", i, conditions[i], want[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expression := range []string{"severity", "severity ==", "severity > 3"} {
+		if _, err := Parse(expression); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", expression)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	row := map[string]any{"severity": "high", "level": int64(3), "note": nil}
+
+	cases := []struct {
+		expression string
+		want       bool
+	}{
+		{"", true},
+		{"severity == high", true},
+		{"severity == low", false},
+		{"level == 3; severity != low", true},
+		{"note != x", true},
+		{"missing == x", false},
+	}
+
+	for _, c := range cases {
+		conditions, err := Parse(c.expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Match(conditions, row); got != c.want {
+			t.Errorf("Match(%q) = %v, want %v", c.expression, got, c.want)
+		}
+	}
+}
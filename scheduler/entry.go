@@ -0,0 +1,24 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Runs returns the previous and next run time of a registered entry. Both are
+// nil when the entry is not scheduled.
+func (s *Scheduler) Runs(id int) (prev *time.Time, next *time.Time) {
+	entry := s.cron.Entry(cron.EntryID(id))
+	if !entry.Valid() {
+		return nil, nil
+	}
+
+	if !entry.Prev.IsZero() {
+		prev = &entry.Prev
+	}
+	if !entry.Next.IsZero() {
+		next = &entry.Next
+	}
+	return prev, next
+}
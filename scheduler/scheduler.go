@@ -1,12 +1,116 @@
 package scheduler
 
 import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"wolfscream/logging"
+
+	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var Cron *cron.Cron
+var tracer = otel.Tracer("wolfscream/scheduler")
+
+// Scheduler runs the jobs of the running scheduled messages. Every run gets
+// a context that is canceled after the job timeout or on Shutdown.
+type Scheduler struct {
+	cron       *cron.Cron
+	jobTimeout time.Duration
+
+	// jobs is the parent of every run context, Shutdown cancels it.
+	jobs       context.Context
+	cancelJobs context.CancelFunc
+
+	// running counts the runs in progress.
+	running atomic.Int64
+}
+
+// New starts a scheduler. A jobTimeout of zero leaves runs unbounded.
+func New(jobTimeout time.Duration) *Scheduler {
+	jobs, cancelJobs := context.WithCancel(context.Background())
+	s := &Scheduler{
+		cron:       cron.New(),
+		jobTimeout: jobTimeout,
+		jobs:       jobs,
+		cancelJobs: cancelJobs,
+	}
+	s.cron.Start()
+	return s
+}
+
+// Add schedules fn with a cron spec and returns the id of the entry. name is
+// the scheduled message the job runs, every run starts a trace with it. The
+// context of a run carries a new run id, see logging.RunID.
+func (s *Scheduler) Add(name, spec string, fn func(ctx context.Context)) (int, error) {
+	id, err := s.cron.AddFunc(spec, func() {
+		if s.jobs.Err() != nil {
+			return
+		}
+
+		ctx, cancel := s.jobs, context.CancelFunc(func() {})
+		if s.jobTimeout > 0 {
+			ctx, cancel = context.WithTimeout(s.jobs, s.jobTimeout)
+		}
+		defer cancel()
+
+		runID := uuid.NewString()
+		ctx = logging.WithRunID(ctx, runID)
+
+		ctx, span := tracer.Start(ctx, "scheduled_message.run", trace.WithAttributes(
+			attribute.String("scheduled_message.name", name),
+			attribute.String("scheduled_message.run_id", runID),
+		))
+		defer span.End()
+
+		s.running.Add(1)
+		defer s.running.Add(-1)
+
+		fn(ctx)
+
+		if err := ctx.Err(); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	})
+	return int(id), err
+}
+
+// Running reports whether the scheduler still starts runs, it stops on
+// Shutdown.
+func (s *Scheduler) Running() bool {
+	return s.jobs.Err() == nil
+}
+
+// RunningJobs returns how many runs are in progress.
+func (s *Scheduler) RunningJobs() int {
+	return int(s.running.Load())
+}
+
+// Entries returns how many jobs are scheduled.
+func (s *Scheduler) Entries() int {
+	return len(s.cron.Entries())
+}
+
+// Remove unschedules an entry. A run in progress isn't interrupted.
+func (s *Scheduler) Remove(id int) {
+	s.cron.Remove(cron.EntryID(id))
+}
+
+// Shutdown stops scheduling runs, cancels the running ones and waits for
+// them to return or for ctx to be done.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	stopped := s.cron.Stop()
+	s.cancelJobs()
 
-func init() {
-    Cron = cron.New()
-    Cron.Start()
-}
\ No newline at end of file
+	select {
+	case <-stopped.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
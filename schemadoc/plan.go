@@ -0,0 +1,182 @@
+package schemadoc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"wolfscream/datatype"
+)
+
+const (
+	CreateTable = "create_table"
+	UpdateTable = "update_table"
+	AddColumn   = "add_column"
+	AlterColumn = "alter_column"
+	DropColumn  = "drop_column"
+	CreateIndex = "create_index"
+	DropIndex   = "drop_index"
+)
+
+// Change is one step of a plan. Diff lists the fields an update_table or
+// alter_column changes.
+type Change struct {
+	Action string `json:"action"`
+	Table  string `json:"table"`
+	Column string `json:"column,omitempty"`
+	Index  string `json:"index,omitempty"`
+	Diff   []Diff `json:"diff,omitempty"`
+}
+
+type Diff struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+func (c Change) String() string {
+	action := strings.ReplaceAll(c.Action, "_", " ")
+	switch {
+	case c.Column != "":
+		return fmt.Sprintf("%s %s.%s", action, c.Table, c.Column)
+	case c.Index != "":
+		return fmt.Sprintf("%s %s on %s", action, c.Index, c.Table)
+	}
+	return fmt.Sprintf("%s %s", action, c.Table)
+}
+
+// Changes returns whether the diff touches field.
+func (c Change) Changes(field string) bool {
+	return slices.ContainsFunc(c.Diff, func(d Diff) bool { return d.Field == field })
+}
+
+// Plan returns the changes that make the tables in current look like the
+// tables in desired. current holds the tables of the document that already
+// exist, by name. Tables missing from the document are never touched,
+// columns and indexes missing from it are only dropped with prune.
+//
+// Columns are matched by name, so a renamed column shows up as a dropped and
+// an added one. Table changes come first, in document order, followed by the
+// dropped and then the created indexes.
+func Plan(current map[string]*Table, desired *Document, prune bool) []Change {
+	changes := []Change{}
+	dropIndexes := []Change{}
+	createIndexes := []Change{}
+
+	for _, table := range desired.Tables {
+		existing := current[table.Name]
+		if existing == nil {
+			existing = &Table{Name: table.Name}
+			changes = append(changes, Change{Action: CreateTable, Table: table.Name})
+			if table.Description != "" {
+				changes = append(changes, Change{
+					Action: UpdateTable,
+					Table:  table.Name,
+					Diff:   []Diff{{Field: "description", From: "", To: table.Description}},
+				})
+			}
+		} else if existing.Description != table.Description {
+			changes = append(changes, Change{
+				Action: UpdateTable,
+				Table:  table.Name,
+				Diff:   []Diff{{Field: "description", From: existing.Description, To: table.Description}},
+			})
+		}
+
+		for _, column := range table.Columns {
+			i := slices.IndexFunc(existing.Columns, func(c Column) bool { return c.Name == column.Name })
+			if i < 0 {
+				changes = append(changes, Change{Action: AddColumn, Table: table.Name, Column: column.Name})
+				continue
+			}
+			if diff := columnDiff(table.Name, existing.Columns[i], column); len(diff) > 0 {
+				changes = append(changes, Change{Action: AlterColumn, Table: table.Name, Column: column.Name, Diff: diff})
+			}
+		}
+
+		if prune {
+			for _, column := range existing.Columns {
+				if !slices.ContainsFunc(table.Columns, func(c Column) bool { return c.Name == column.Name }) {
+					changes = append(changes, Change{Action: DropColumn, Table: table.Name, Column: column.Name})
+				}
+			}
+		}
+
+		for _, index := range existing.Indexes {
+			i := slices.IndexFunc(table.Indexes, func(idx Index) bool { return idx.Name == index.Name })
+			if (i < 0 && prune) || (i >= 0 && !sameIndex(index, table.Indexes[i])) {
+				dropIndexes = append(dropIndexes, Change{Action: DropIndex, Table: table.Name, Index: index.Name})
+			}
+		}
+		for _, index := range table.Indexes {
+			i := slices.IndexFunc(existing.Indexes, func(idx Index) bool { return idx.Name == index.Name })
+			if i < 0 || !sameIndex(existing.Indexes[i], index) {
+				createIndexes = append(createIndexes, Change{Action: CreateIndex, Table: table.Name, Index: index.Name})
+			}
+		}
+	}
+
+	changes = append(changes, dropIndexes...)
+	return append(changes, createIndexes...)
+}
+
+func columnDiff(table string, from Column, to Column) []Diff {
+	diff := []Diff{}
+
+	fromSpec, toSpec := from.Spec(table), to.Spec(table)
+	if !datatype.Equal(fromSpec, toSpec) {
+		diff = append(diff, Diff{Field: "type", From: typeName(fromSpec), To: typeName(toSpec)})
+	}
+
+	// the current default is read as the new type, one that doesn't fit it
+	// anymore has to be replaced
+	fromDefault, err := DefaultText(toSpec, from.Default)
+	toDefault, _ := DefaultText(toSpec, to.Default)
+	if err != nil || !equalText(fromDefault, toDefault) {
+		diff = append(diff, Diff{Field: "default", From: from.Default, To: to.Default})
+	}
+
+	if from.IsNullable() != to.IsNullable() {
+		diff = append(diff, Diff{Field: "nullable", From: from.IsNullable(), To: to.IsNullable()})
+	}
+	if from.Unique != to.Unique {
+		diff = append(diff, Diff{Field: "unique", From: from.Unique, To: to.Unique})
+	}
+	if from.Check != to.Check {
+		diff = append(diff, Diff{Field: "check", From: from.Check, To: to.Check})
+	}
+	if from.References != to.References || from.OnDelete != to.OnDelete {
+		diff = append(diff, Diff{
+			Field: "references",
+			From:  referenceName(from),
+			To:    referenceName(to),
+		})
+	}
+
+	return diff
+}
+
+func typeName(spec datatype.Spec) string {
+	if spec.Type == "enum" {
+		return "enum(" + strings.Join(spec.EnumValues, ", ") + ")"
+	}
+	return strings.ToLower(datatype.SQL(spec))
+}
+
+func referenceName(column Column) string {
+	if column.References == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s on delete %s", column.References, column.OnDelete)
+}
+
+func equalText(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func sameIndex(a Index, b Index) bool {
+	return a.Method == b.Method && a.Unique == b.Unique && slices.Equal(a.Columns, b.Columns)
+}
@@ -0,0 +1,83 @@
+package schemadoc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	current := map[string]*Table{
+		"alerts": {
+			Name: "alerts",
+			Columns: []Column{
+				{Name: "severity", Type: "varchar", Length: intPtr(16), Default: "low", Nullable: boolPtr(true)},
+				{Name: "score", Type: "int4", Default: json.Number("1"), Nullable: boolPtr(true)},
+				{Name: "legacy", Type: "text", Nullable: boolPtr(true)},
+			},
+			Indexes: []Index{
+				{Name: "alerts_severity_idx", Method: "btree", Columns: []string{"severity"}},
+				{Name: "alerts_score_idx", Method: "btree", Columns: []string{"score"}},
+				{Name: "alerts_old_idx", Method: "btree", Columns: []string{"legacy"}},
+			},
+		},
+	}
+
+	desired := &Document{Tables: []Table{
+		{
+			Name:        "alerts",
+			Description: "Wazuh alerts",
+			Columns: []Column{
+				{Name: "severity", Type: "varchar", Length: intPtr(32), Default: "low", Nullable: boolPtr(false)},
+				{Name: "score", Type: "int4", Default: "1"},
+				{Name: "agent_id", Type: "int8", References: "agents", OnDelete: "cascade"},
+			},
+			Indexes: []Index{
+				{Name: "alerts_severity_idx", Method: "btree", Columns: []string{"severity"}},
+				{Name: "alerts_score_idx", Method: "btree", Unique: true, Columns: []string{"score"}},
+			},
+		},
+		{Name: "agents"},
+	}}
+
+	want := []struct{ action, table, name string }{
+		{UpdateTable, "alerts", ""},
+		{AlterColumn, "alerts", "severity"},
+		{AddColumn, "alerts", "agent_id"},
+		{DropColumn, "alerts", "legacy"},
+		{CreateTable, "agents", ""},
+		{DropIndex, "alerts", "alerts_score_idx"},
+		{DropIndex, "alerts", "alerts_old_idx"},
+		{CreateIndex, "alerts", "alerts_score_idx"},
+	}
+
+	changes := Plan(current, desired, true)
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		c := changes[i]
+		name := c.Column + c.Index
+		if c.Action != w.action || c.Table != w.table || name != w.name {
+			t.Errorf("change %d = %s, want %s %s %s", i, c, w.action, w.table, w.name)
+		}
+	}
+
+	severity := changes[1]
+	if len(severity.Diff) != 2 || !severity.Changes("type") || !severity.Changes("nullable") {
+		t.Errorf("severity diff = %+v, want type and nullable", severity.Diff)
+	}
+}
+
+func TestPlanWithoutPrune(t *testing.T) {
+	current := map[string]*Table{
+		"alerts": {
+			Name:    "alerts",
+			Columns: []Column{{Name: "legacy", Type: "text"}},
+			Indexes: []Index{{Name: "alerts_legacy_idx", Method: "btree", Columns: []string{"legacy"}}},
+		},
+	}
+
+	if changes := Plan(current, &Document{Tables: []Table{{Name: "alerts"}}}, false); len(changes) != 0 {
+		t.Errorf("got %v, want no changes", changes)
+	}
+}
@@ -0,0 +1,309 @@
+// Package schemadoc is the declarative form of user defined tables: a JSON or
+// YAML document listing tables with their columns, constraints and indexes,
+// and the plan of changes that brings the database to match it.
+package schemadoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"wolfscream/check"
+	"wolfscream/datatype"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// idColumn is the primary key every table gets. It isn't part of documents.
+const idColumn = "id"
+
+var onDeleteActions = []string{"restrict", "cascade", "set null"}
+
+type Document struct {
+	Tables []Table `json:"tables" validate:"required,dive"`
+}
+
+type Table struct {
+	Name        string   `json:"name" validate:"required,snakecase,max=63"`
+	Description string   `json:"description,omitempty"`
+	Columns     []Column `json:"columns" validate:"dive"`
+	Indexes     []Index  `json:"indexes,omitempty" validate:"dive"`
+}
+
+// Column is a column with its type and constraints. Nullable defaults to
+// true, OnDelete to restrict when References is set.
+type Column struct {
+	Name       string   `json:"name" validate:"required,snakecase,max=63"`
+	Type       string   `json:"type" validate:"required"`
+	Length     *int     `json:"length,omitempty"`
+	Precision  *int     `json:"precision,omitempty"`
+	Scale      *int     `json:"scale,omitempty"`
+	Values     []string `json:"values,omitempty"`
+	Default    any      `json:"default,omitempty"`
+	Nullable   *bool    `json:"nullable,omitempty"`
+	Unique     bool     `json:"unique,omitempty"`
+	Check      string   `json:"check,omitempty"`
+	References string   `json:"references,omitempty"`
+	OnDelete   string   `json:"on_delete,omitempty"`
+}
+
+// Index is a btree or gin index. Method defaults to btree.
+type Index struct {
+	Name    string   `json:"name" validate:"required,snakecase,max=63"`
+	Method  string   `json:"method,omitempty" validate:"omitempty,oneof=btree gin"`
+	Unique  bool     `json:"unique,omitempty"`
+	Columns []string `json:"columns" validate:"required,min=1,dive,required"`
+}
+
+// Spec returns the type of the column in table.
+func (c Column) Spec(table string) datatype.Spec {
+	spec := datatype.Spec{
+		Type:       c.Type,
+		Length:     c.Length,
+		Precision:  c.Precision,
+		Scale:      c.Scale,
+		EnumValues: c.Values,
+	}
+	if spec.Type == "enum" {
+		spec.EnumType = datatype.EnumTypeName(table, c.Name)
+	}
+	return spec
+}
+
+func (c Column) IsNullable() bool {
+	return c.Nullable == nil || *c.Nullable
+}
+
+// Parse reads a document in format. YAML is converted to JSON first so both
+// formats are decoded the same way, numbers included. Unknown fields are
+// rejected to catch typos.
+func Parse(data []byte, format string) (*Document, error) {
+	switch format {
+	case FormatJSON:
+	case FormatYAML:
+		var value any
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %v", err)
+		}
+		converted, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid YAML: %v", err)
+		}
+		data = converted
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid document: %v", err)
+	}
+
+	for i := range doc.Tables {
+		for j := range doc.Tables[i].Columns {
+			column := &doc.Tables[i].Columns[j]
+			if column.References != "" && column.OnDelete == "" {
+				column.OnDelete = "restrict"
+			}
+		}
+		for j := range doc.Tables[i].Indexes {
+			if doc.Tables[i].Indexes[j].Method == "" {
+				doc.Tables[i].Indexes[j].Method = "btree"
+			}
+		}
+	}
+
+	return &doc, nil
+}
+
+// Encode writes doc in format. YAML is produced from the JSON encoding, which
+// keeps the field names and the number formatting of the JSON form.
+func Encode(w io.Writer, doc *Document, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	case FormatYAML:
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		// JSON is YAML in flow style, decoding it keeps the types and
+		// clearing the styles gives block style output
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return err
+		}
+		clearStyle(&node)
+
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return fmt.Errorf("unknown format: %s", format)
+}
+
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// DefaultText returns the text stored for a default value, nil for no
+// default.
+func DefaultText(spec datatype.Spec, value any) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	coerced, err := datatype.Coerce(spec, value)
+	if err != nil {
+		return nil, err
+	}
+	text := datatype.Text(coerced)
+	return &text, nil
+}
+
+// DefaultValue converts the text stored for a default back to a document
+// value, the inverse of DefaultText.
+func DefaultValue(spec datatype.Spec, text string) any {
+	switch spec.Type {
+	case "int4", "int8", "float4", "float8", "numeric":
+		return json.Number(text)
+	case "bool":
+		if coerced, err := datatype.CoerceText(spec, text); err == nil {
+			return coerced
+		}
+	case "jsonb":
+		if json.Valid([]byte(text)) {
+			return json.RawMessage(text)
+		}
+	case "text[]":
+		return datatype.Output(spec, []byte(text))
+	}
+	return text
+}
+
+// Validate checks the parts of a document that struct tags can't: types,
+// defaults, constraints and index columns. References to tables outside the
+// document are left to the caller.
+func Validate(doc *Document) error {
+	errs := []error{}
+	fail := func(path string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	tables := map[string]bool{}
+	indexes := map[string]bool{}
+
+	for _, table := range doc.Tables {
+		if tables[table.Name] {
+			fail(table.Name, "duplicate table")
+		}
+		tables[table.Name] = true
+
+		columns := map[string]Column{}
+		for _, column := range table.Columns {
+			path := table.Name + "." + column.Name
+			if column.Name == idColumn {
+				fail(path, "id is added to every table and can't be declared")
+				continue
+			}
+			if _, ok := columns[column.Name]; ok {
+				fail(path, "duplicate column")
+			}
+			columns[column.Name] = column
+
+			spec := column.Spec(table.Name)
+			if err := datatype.Validate(spec); err != nil {
+				fail(path, "%v", err)
+				continue
+			}
+
+			if _, err := DefaultText(spec, column.Default); err != nil {
+				fail(path, "invalid default: %v", err)
+			}
+
+			if column.Check != "" {
+				if err := validateCheck(spec, column.Check); err != nil {
+					fail(path, "%v", err)
+				}
+			}
+
+			if column.OnDelete != "" && column.References == "" {
+				fail(path, "on_delete requires references")
+			}
+			if column.References != "" {
+				if column.Type != "int8" && column.Type != "int4" {
+					fail(path, "only int8 and int4 columns can reference a table")
+				}
+				if !slices.Contains(onDeleteActions, column.OnDelete) {
+					fail(path, "on_delete must be restrict, cascade or set null")
+				}
+				if column.OnDelete == "set null" && !column.IsNullable() {
+					fail(path, "on_delete set null requires a nullable column")
+				}
+			}
+		}
+
+		for _, index := range table.Indexes {
+			path := table.Name + "." + index.Name
+			if indexes[index.Name] {
+				fail(path, "duplicate index")
+			}
+			indexes[index.Name] = true
+
+			if index.Unique && index.Method != "btree" {
+				fail(path, "only btree indexes can be unique")
+			}
+			for _, name := range index.Columns {
+				column, ok := columns[name]
+				switch {
+				case name == idColumn:
+					if index.Method == "gin" {
+						fail(path, "gin indexes only apply to jsonb and text[] columns")
+					}
+				case !ok:
+					fail(path, "unknown column %s", name)
+				case index.Method == "gin" && column.Type != "jsonb" && column.Type != "text[]":
+					fail(path, "gin indexes only apply to jsonb and text[] columns, %s is %s", name, column.Type)
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateCheck(spec datatype.Spec, expression string) error {
+	if spec.Type == "jsonb" || spec.Type == "text[]" {
+		return fmt.Errorf("check expressions are not supported on %s columns", spec.Type)
+	}
+	conditions, err := check.Parse(expression)
+	if err != nil {
+		return fmt.Errorf("invalid check: %v", err)
+	}
+	_, err = check.SQL(conditions, "value", func(value string) (string, error) {
+		return datatype.TextLiteral(spec, value)
+	})
+	if err != nil {
+		return fmt.Errorf("invalid check: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package schemadoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"wolfscream/datatype"
+)
+
+const alertsYAML = `
+tables:
+  - name: alerts
+    description: Wazuh alerts
+    columns:
+      - name: severity
+        type: enum
+        values: [low, high]
+        default: low
+        nullable: false
+      - name: score
+        type: numeric
+        precision: 10
+        scale: 2
+        default: 1.50
+        check: ">= 0"
+      - name: agent_id
+        type: int8
+        references: agents
+      - name: tags
+        type: text[]
+    indexes:
+      - name: alerts_tags_idx
+        method: gin
+        columns: [tags]
+  - name: agents
+    columns: []
+`
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestParseYAML(t *testing.T) {
+	doc, err := Parse([]byte(alertsYAML), FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := doc.Tables[0]
+	if alerts.Name != "alerts" || len(alerts.Columns) != 4 || len(doc.Tables) != 2 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	if score := alerts.Columns[1]; score.Default != json.Number("1.5") || *score.Precision != 10 {
+		t.Errorf("score = %+v", score)
+	}
+	if agent := alerts.Columns[2]; agent.OnDelete != "restrict" {
+		t.Errorf("on_delete = %q, want the restrict default", agent.OnDelete)
+	}
+	if alerts.Columns[0].IsNullable() || !alerts.Columns[3].IsNullable() {
+		t.Error("nullable isn't read")
+	}
+
+	if err := Validate(doc); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	if _, err := Parse([]byte(`{"tables": [{"name": "alerts", "colums": []}]}`), FormatJSON); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	doc, err := Parse([]byte(alertsYAML), FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, doc, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if format == FormatYAML && strings.Contains(buf.String(), "{") {
+			t.Errorf("YAML isn't in block style:\n%s", buf.String())
+		}
+
+		parsed, err := Parse(buf.Bytes(), format)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", format, err, buf.String())
+		}
+		if !reflect.DeepEqual(parsed, doc) {
+			t.Errorf("%s round trip changed the document:\n%s", format, buf.String())
+		}
+	}
+}
+
+func TestEncodeYAMLQuotesStrings(t *testing.T) {
+	doc := &Document{Tables: []Table{{Name: "alerts", Columns: []Column{
+		{Name: "code", Type: "text", Default: "001"},
+		{Name: "flag", Type: "text", Default: "true"},
+	}}}}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(buf.Bytes(), FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Tables[0].Columns[0].Default != "001" || parsed.Tables[0].Columns[1].Default != "true" {
+		t.Errorf("string defaults changed type:\n%s", buf.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		table Table
+		err   string
+	}{
+		{"id column", Table{Name: "a", Columns: []Column{{Name: "id", Type: "int8"}}}, "can't be declared"},
+		{"duplicate column", Table{Name: "a", Columns: []Column{{Name: "b", Type: "text"}, {Name: "b", Type: "text"}}}, "duplicate column"},
+		{"unknown type", Table{Name: "a", Columns: []Column{{Name: "b", Type: "money"}}}, "unknown column type"},
+		{"default", Table{Name: "a", Columns: []Column{{Name: "b", Type: "int4", Default: "x"}}}, "invalid default"},
+		{"check value", Table{Name: "a", Columns: []Column{{Name: "b", Type: "int4", Check: ">= x"}}}, "invalid check"},
+		{"reference type", Table{Name: "a", Columns: []Column{{Name: "b", Type: "text", References: "c", OnDelete: "restrict"}}}, "only int8 and int4"},
+		{"set null", Table{Name: "a", Columns: []Column{{Name: "b", Type: "int8", References: "c", OnDelete: "set null", Nullable: boolPtr(false)}}}, "requires a nullable column"},
+		{"index column", Table{Name: "a", Indexes: []Index{{Name: "i", Method: "btree", Columns: []string{"b"}}}}, "unknown column b"},
+		{"gin", Table{Name: "a", Columns: []Column{{Name: "b", Type: "text"}}, Indexes: []Index{{Name: "i", Method: "gin", Columns: []string{"b"}}}}, "gin indexes"},
+		{"unique gin", Table{Name: "a", Columns: []Column{{Name: "b", Type: "jsonb"}}, Indexes: []Index{{Name: "i", Method: "gin", Unique: true, Columns: []string{"b"}}}}, "only btree"},
+	}
+
+	for _, test := range tests {
+		err := Validate(&Document{Tables: []Table{test.table}})
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got %v, want an error containing %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestDefaultValue(t *testing.T) {
+	tests := []struct {
+		spec datatype.Spec
+		text string
+		want any
+	}{
+		{datatype.Spec{Type: "int8"}, "42", json.Number("42")},
+		{datatype.Spec{Type: "bool"}, "true", true},
+		{datatype.Spec{Type: "jsonb"}, `{"a":1}`, json.RawMessage(`{"a":1}`)},
+		{datatype.Spec{Type: "text[]"}, `{"a","b c"}`, []any{"a", "b c"}},
+		{datatype.Spec{Type: "text"}, "001", "001"},
+	}
+
+	for _, test := range tests {
+		got := DefaultValue(test.spec, test.text)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("DefaultValue(%s, %q) = %#v, want %#v", test.spec.Type, test.text, got, test.want)
+			continue
+		}
+
+		// the value must give back the same text
+		text, err := DefaultText(test.spec, got)
+		if err != nil || *text != test.text {
+			t.Errorf("DefaultText(%s, %#v) = %v, %v, want %q", test.spec.Type, got, text, err, test.text)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"wolfscream/datatype"
+
+	"github.com/lib/pq"
+)
+
+// Column is a column of a user defined table. Managed marks the id column
+// added by TableStore.Create, which isn't registered and can't be written
+// through the API.
+type Column struct {
+	Name string
+	datatype.Spec
+	Managed bool
+}
+
+type ColumnReferences struct {
+	Table    string `json:"table"`
+	OnDelete string `json:"on_delete"`
+}
+
+// ColumnDescription is a registered column with its default and constraints.
+type ColumnDescription struct {
+	Name         string            `json:"name"`
+	Type         string            `json:"type"`
+	Length       *int              `json:"length"`
+	Precision    *int              `json:"precision,omitempty"`
+	Scale        *int              `json:"scale,omitempty"`
+	Values       []string          `json:"values,omitempty"`
+	DefaultValue any               `json:"default"`
+	Nullable     bool              `json:"nullable"`
+	Unique       bool              `json:"unique"`
+	Check        *string           `json:"check"`
+	References   *ColumnReferences `json:"references"`
+}
+
+// Constraints is a change to the constraints recorded for a column. Nil
+// fields are left as they are, an empty Check or References removes it.
+type Constraints struct {
+	Nullable   *bool
+	Unique     *bool
+	Check      *string
+	References *string
+	OnDelete   string
+}
+
+// ColumnStore changes the columns of user defined tables and keeps
+// user_defined_column in step with them.
+type ColumnStore interface {
+	// Load returns the columns of a table in the order they were added,
+	// starting with the managed id column when the table has one. It
+	// returns ErrNotFound when the table isn't registered.
+	Load(ctx context.Context, db DBTX, table string) ([]Column, error)
+	// Describe returns the registered columns of a table with their
+	// constraints, in the order they were added.
+	Describe(ctx context.Context, db DBTX, table string) ([]ColumnDescription, error)
+	// Get returns ErrNotFound when the column isn't registered.
+	Get(ctx context.Context, db DBTX, table string, column string) (*ColumnDescription, error)
+	// Add adds a nullable column without constraints and registers it,
+	// creating its enum type first. defaultText is the default in the text
+	// form of datatype.Text.
+	Add(ctx context.Context, db DBTX, table string, column Column, defaultText *string) error
+	// SetDefault sets the default of a column, or drops it when defaultText
+	// is nil.
+	SetDefault(ctx context.Context, db DBTX, table string, column Column, defaultText *string) error
+	// SetType records the type a column was converted to and its new
+	// default.
+	SetType(ctx context.Context, db DBTX, table string, column string, spec datatype.Spec, defaultText *string) error
+	// SetConstraints records the constraints of a column. It doesn't
+	// change the table.
+	SetConstraints(ctx context.Context, db DBTX, table string, column string, c Constraints) error
+	// Rename renames a column, its registration and its place in the
+	// registered indexes.
+	Rename(ctx context.Context, db DBTX, table string, column string, newName string) error
+	// Drop drops a column with its enum type and removes it and its
+	// indexes from the metadata.
+	Drop(ctx context.Context, db DBTX, table string, column string) error
+	// Register registers a column that already exists.
+	Register(ctx context.Context, db DBTX, table string, column Column, nullable bool) error
+	// Sync records the type and nullability a column has in the catalog.
+	Sync(ctx context.Context, db DBTX, table string, column Column, nullable bool) error
+	// Unregister removes the registration of a column without touching the
+	// table.
+	Unregister(ctx context.Context, db DBTX, table string, column string) error
+}
+
+type columnStore struct{}
+
+func (columnStore) Load(ctx context.Context, db DBTX, table string) ([]Column, error) {
+	var (
+		tableId int
+		hasId   bool
+	)
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			udt.id,
+			EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = udt.name AND column_name = $2
+			)
+		FROM user_defined_table udt
+		WHERE udt.name = $1
+	`, table, IdColumn).Scan(&tableId, &hasId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to query table: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT name, type, length, "precision", scale, enum_type, enum_values
+		FROM user_defined_column
+		WHERE user_defined_table_id = $1
+		ORDER BY id ASC
+	`, tableId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := []Column{}
+	if hasId {
+		columns = append(columns, Column{Name: IdColumn, Spec: datatype.Spec{Type: "int8"}, Managed: true})
+	}
+
+	for rows.Next() {
+		var (
+			column   Column
+			enumType sql.NullString
+		)
+		if err := rows.Scan(&column.Name, &column.Type, &column.Length, &column.Precision, &column.Scale, &enumType, (*pq.StringArray)(&column.EnumValues)); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		column.EnumType = enumType.String
+		columns = append(columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return columns, nil
+}
+
+const describeColumnsQuery = `
+	SELECT
+		udc.name,
+		udc.type,
+		udc.length,
+		udc."precision",
+		udc.scale,
+		udc.enum_values,
+		udc.default_value,
+		udc.is_nullable,
+		udc.is_unique,
+		udc.check_expression,
+		udc.references_table,
+		udc.on_delete
+	FROM user_defined_column udc
+		JOIN user_defined_table udt ON udc.user_defined_table_id = udt.id
+	WHERE udt.name = $1`
+
+func (columnStore) Describe(ctx context.Context, db DBTX, table string) ([]ColumnDescription, error) {
+	rows, err := db.QueryContext(ctx, describeColumnsQuery+" ORDER BY udc.id ASC;", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := []ColumnDescription{}
+
+	for rows.Next() {
+		column, err := scanColumnDescription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, *column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return columns, nil
+}
+
+func (columnStore) Get(ctx context.Context, db DBTX, table string, column string) (*ColumnDescription, error) {
+	description, err := scanColumnDescription(db.QueryRowContext(ctx, describeColumnsQuery+" AND udc.name = $2;", table, column))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return description, nil
+}
+
+func scanColumnDescription(row interface{ Scan(...any) error }) (*ColumnDescription, error) {
+	var (
+		column     ColumnDescription
+		references sql.NullString
+		onDelete   sql.NullString
+	)
+	if err := row.Scan(
+		&column.Name, &column.Type, &column.Length, &column.Precision, &column.Scale, (*pq.StringArray)(&column.Values), &column.DefaultValue,
+		&column.Nullable, &column.Unique, &column.Check, &references, &onDelete,
+	); err != nil {
+		return nil, err
+	}
+	if references.Valid {
+		column.References = &ColumnReferences{Table: references.String, OnDelete: onDelete.String}
+	}
+	return &column, nil
+}
+
+func (s columnStore) Add(ctx context.Context, db DBTX, table string, column Column, defaultText *string) error {
+	query := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, pq.QuoteIdentifier(table), pq.QuoteIdentifier(column.Name), datatype.SQL(column.Spec))
+	if defaultText != nil {
+		literal, err := datatype.TextLiteral(column.Spec, *defaultText)
+		if err != nil {
+			return err
+		}
+		query += " DEFAULT " + literal
+	}
+
+	if column.Type == "enum" {
+		if _, err := db.ExecContext(ctx, datatype.CreateEnum(column.Spec)); err != nil {
+			return fmt.Errorf("failed to create enum type: %w", err)
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to add column: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO user_defined_column (user_defined_table_id, name, type, length, "precision", scale, enum_type, enum_values, default_value, is_nullable)
+		VALUES ((SELECT id FROM user_defined_table WHERE name = $1), $2, $3, $4, $5, $6, $7, $8, $9, TRUE)`,
+		table, column.Name, column.Type, column.Length, column.Precision, column.Scale, enumType(column.Spec), pq.StringArray(column.EnumValues), defaultText); err != nil {
+		return fmt.Errorf("failed to insert into columns: %w", err)
+	}
+
+	return nil
+}
+
+func (columnStore) SetDefault(ctx context.Context, db DBTX, table string, column Column, defaultText *string) error {
+	action := "DROP DEFAULT"
+	if defaultText != nil {
+		literal, err := datatype.TextLiteral(column.Spec, *defaultText)
+		if err != nil {
+			return err
+		}
+		action = "SET DEFAULT " + literal
+	}
+
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", pq.QuoteIdentifier(table), pq.QuoteIdentifier(column.Name), action)); err != nil {
+		return err
+	}
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE user_defined_column SET default_value = $1
+		WHERE user_defined_table_id = (SELECT id FROM user_defined_table WHERE name = $2)
+			AND name = $3
+	`, defaultText, table, column.Name)
+	return err
+}
+
+func (columnStore) SetType(ctx context.Context, db DBTX, table string, column string, spec datatype.Spec, defaultText *string) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE user_defined_column SET
+			type = $1,
+			length = $2,
+			"precision" = $3,
+			scale = $4,
+			enum_type = $5,
+			enum_values = $6,
+			default_value = $7
+		WHERE user_defined_table_id = (SELECT id FROM user_defined_table WHERE name = $8)
+			AND name = $9
+	`, spec.Type, spec.Length, spec.Precision, spec.Scale, enumType(spec), pq.StringArray(spec.EnumValues), defaultText, table, column)
+	return err
+}
+
+func (columnStore) SetConstraints(ctx context.Context, db DBTX, table string, column string, c Constraints) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE user_defined_column SET
+			is_nullable = COALESCE($1, is_nullable),
+			is_unique = COALESCE($2, is_unique),
+			check_expression = CASE WHEN $3::text IS NULL THEN check_expression ELSE NULLIF($3, '') END,
+			references_table = CASE WHEN $4::text IS NULL THEN references_table ELSE NULLIF($4, '') END,
+			on_delete = CASE WHEN $4::text IS NULL THEN on_delete ELSE NULLIF($5, '') END
+		WHERE user_defined_table_id = (SELECT id FROM user_defined_table WHERE name = $6)
+			AND name = $7
+	`, c.Nullable, c.Unique, c.Check, c.References, c.OnDelete, table, column)
+	return err
+}
+
+func (columnStore) Rename(ctx context.Context, db DBTX, table string, column string, newName string) error {
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", pq.QuoteIdentifier(table), pq.QuoteIdentifier(column), pq.QuoteIdentifier(newName))); err != nil {
+		return fmt.Errorf("failed to update column: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		UPDATE user_defined_index SET columns = array_replace(columns, $1, $2)
+		WHERE user_defined_table_id = (SELECT id FROM user_defined_table WHERE name = $3)
+	`, column, newName, table); err != nil {
+		return fmt.Errorf("failed to update indexes: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		UPDATE user_defined_column SET name = $1
+		WHERE user_defined_table_id = (SELECT id FROM user_defined_table WHERE name = $2)
+			AND name = $3
+	`, newName, table, column); err != nil {
+		return fmt.Errorf("failed to update column: %w", err)
+	}
+
+	return nil
+}
+
+func (s columnStore) Drop(ctx context.Context, db DBTX, table string, column string) error {
+	var enumType sql.NullString
+	err := db.QueryRowContext(ctx, `
+		SELECT udc.enum_type
+		FROM user_defined_column udc
+			JOIN user_defined_table udt ON udc.user_defined_table_id = udt.id
+		WHERE udt.name = $1 AND udc.name = $2
+	`, table, column).Scan(&enumType)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to query column: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", pq.QuoteIdentifier(table), pq.QuoteIdentifier(column))); err != nil {
+		return fmt.Errorf("failed to drop column: %w", err)
+	}
+
+	if err := s.Unregister(ctx, db, table, column); err != nil {
+		return fmt.Errorf("failed to delete column: %w", err)
+	}
+
+	// Postgres drops the indexes of the column with it
+	if _, err := db.ExecContext(ctx, `
+		DELETE FROM user_defined_index
+		WHERE user_defined_table_id = (SELECT id FROM user_defined_table WHERE name = $1)
+			AND $2 = ANY(columns)
+	`, table, column); err != nil {
+		return fmt.Errorf("failed to delete indexes: %w", err)
+	}
+
+	if enumType.Valid {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP TYPE IF EXISTS %s", pq.QuoteIdentifier(enumType.String))); err != nil {
+			return fmt.Errorf("failed to drop enum type: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (columnStore) Register(ctx context.Context, db DBTX, table string, column Column, nullable bool) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO user_defined_column (user_defined_table_id, name, type, length, "precision", scale, enum_type, enum_values, is_nullable)
+		VALUES ((SELECT id FROM user_defined_table WHERE name = $1), $2, $3, $4, $5, $6, $7, $8, $9)
+	`, table, column.Name, column.Type, column.Length, column.Precision, column.Scale, enumType(column.Spec), pq.StringArray(column.EnumValues), nullable)
+	return err
+}
+
+func (columnStore) Sync(ctx context.Context, db DBTX, table string, column Column, nullable bool) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE user_defined_column SET
+			type = $3,
+			length = $4,
+			"precision" = $5,
+			scale = $6,
+			enum_type = $7,
+			enum_values = $8,
+			is_nullable = $9
+		WHERE user_defined_table_id = (SELECT id FROM user_defined_table WHERE name = $1) AND name = $2
+	`, table, column.Name, column.Type, column.Length, column.Precision, column.Scale, enumType(column.Spec), pq.StringArray(column.EnumValues), nullable)
+	return err
+}
+
+func (columnStore) Unregister(ctx context.Context, db DBTX, table string, column string) error {
+	_, err := db.ExecContext(ctx, `
+		DELETE FROM user_defined_column
+		WHERE user_defined_table_id = (SELECT id FROM user_defined_table WHERE name = $1) AND name = $2
+	`, table, column)
+	return err
+}
+
+// enumType is the enum_type of a column, NULL when it isn't an enum.
+func enumType(spec datatype.Spec) *string {
+	if spec.EnumType == "" {
+		return nil
+	}
+	return &spec.EnumType
+}
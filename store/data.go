@@ -0,0 +1,446 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"wolfscream/datatype"
+
+	"github.com/lib/pq"
+)
+
+// RowError is returned when Postgres rejects a row because of its values,
+// such as a value of the wrong type or a constraint violation. Column is
+// set when Postgres names it.
+type RowError struct {
+	Column string
+	Err    error
+}
+
+func (e *RowError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// rowError wraps the errors caused by the values of a row in a RowError and
+// returns the others as they are.
+func rowError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && (pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23") {
+		return &RowError{Column: pqErr.Column, Err: errors.New(pqErr.Message)}
+	}
+	return err
+}
+
+// RowWriter inserts rows into a user defined table. Insert writes the named
+// columns of a row, the columns left out get their default. Flush must be
+// called once the rows are written.
+type RowWriter interface {
+	Insert(ctx context.Context, columns []string, values []any) error
+	Flush(ctx context.Context) error
+}
+
+// Row is a row of a user defined table keyed by column, with the values
+// converted for JSON by datatype.Output.
+type Row = map[string]any
+
+// Rows iterates over the rows of an export. Values is only valid until the
+// next call to Next.
+type Rows interface {
+	Next() bool
+	Values() ([]any, error)
+	Err() error
+	Close() error
+}
+
+// DataStore reads and writes the rows of the user defined tables.
+type DataStore interface {
+	// Count counts the rows matching the filter of q, ignoring pagination.
+	Count(ctx context.Context, db DBTX, q *DataQuery) (int, error)
+	// Page returns a page of the rows matching q and the cursor of the next
+	// page, nil on the last page.
+	Page(ctx context.Context, db DBTX, q *DataQuery) ([]Row, *DataCursor, error)
+	// Export returns every row matching the filter of q, with the values of
+	// its fields in order.
+	Export(ctx context.Context, db DBTX, q *DataQuery) (Rows, error)
+	// Scan calls fn with every row of table, values as they are scanned
+	// except bytes which are turned into strings.
+	Scan(ctx context.Context, db DBTX, table string, fn func(row map[string]any)) error
+
+	// Get returns the fields of the row with id, or ErrNotFound.
+	Get(ctx context.Context, db DBTX, table string, columns []Column, fields []string, id int64) (Row, error)
+	// Insert inserts a row of values and returns it.
+	Insert(ctx context.Context, db DBTX, table string, columns []Column, values map[string]any) (Row, error)
+	// Update writes values to the row with id and returns it, or ErrNotFound.
+	// With replace the writable columns missing from values are reset to
+	// their default.
+	Update(ctx context.Context, db DBTX, table string, columns []Column, id int64, values map[string]any, replace bool) (Row, error)
+	// Delete deletes the row with id, or returns ErrNotFound.
+	Delete(ctx context.Context, db DBTX, table string, id int64) error
+
+	// Copier streams the rows with COPY. A rejected row fails the COPY,
+	// which is reported by Insert or Flush.
+	Copier(db DBTX, table string) RowWriter
+	// Inserter inserts every row in its own savepoint of the transaction
+	// db, a rejected row returns a RowError and leaves the other rows in.
+	Inserter(db DBTX, table string) RowWriter
+}
+
+type dataStore struct{}
+
+func (dataStore) Count(ctx context.Context, db DBTX, q *DataQuery) (int, error) {
+	var total int
+	query, args := q.countQuery()
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (dataStore) Page(ctx context.Context, db DBTX, q *DataQuery) ([]Row, *DataCursor, error) {
+	query, args := q.selectQuery()
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	specs := q.fieldSpecs()
+	results := []Row{}
+	var last DataCursor
+
+	for rows.Next() {
+		if len(results) == q.Limit {
+			return results, &last, nil
+		}
+
+		cursor := DataCursor{Sort: q.SortSpec(), Values: make([]*string, len(q.Sort))}
+		cursorPtrs := []any{}
+		for i := range cursor.Values {
+			cursorPtrs = append(cursorPtrs, &cursor.Values[i])
+		}
+		cursorPtrs = append(cursorPtrs, &cursor.Tie)
+
+		row, err := scanRow(rows, q.Fields, specs, cursorPtrs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan: %w", err)
+		}
+
+		results = append(results, row)
+		last = cursor
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return results, nil, nil
+}
+
+func (dataStore) Export(ctx context.Context, db DBTX, q *DataQuery) (Rows, error) {
+	query, args := q.exportQuery()
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := &exportRows{Rows: rows, specs: q.fieldSpecs(), values: make([]any, len(q.Fields))}
+	exported.pointers = make([]any, len(q.Fields))
+	for i := range exported.values {
+		exported.pointers[i] = &exported.values[i]
+	}
+	return exported, nil
+}
+
+type exportRows struct {
+	*sql.Rows
+	specs    []datatype.Spec
+	values   []any
+	pointers []any
+}
+
+func (r *exportRows) Values() ([]any, error) {
+	if err := r.Scan(r.pointers...); err != nil {
+		return nil, err
+	}
+	for i := range r.values {
+		r.values[i] = datatype.Output(r.specs[i], r.values[i])
+	}
+	return r.values, nil
+}
+
+func (dataStore) Scan(ctx context.Context, db DBTX, table string, fn func(row map[string]any)) error {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s", pq.QuoteIdentifier(table)))
+	if err != nil {
+		return fmt.Errorf("failed to query table: %w", err)
+	}
+	defer rows.Close()
+
+	columns, _ := rows.Columns()
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			continue
+		}
+
+		row := make(map[string]any)
+		for i, column := range columns {
+			// numeric, uuid, enum and jsonb values are scanned as bytes
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		fn(row)
+	}
+
+	// a canceled query ends the rows early, the caller must not use a
+	// partial result
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read table: %w", err)
+	}
+	return nil
+}
+
+func (dataStore) Get(ctx context.Context, db DBTX, table string, columns []Column, fields []string, id int64) (Row, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1;", quoteIdentifiers(fields), pq.QuoteIdentifier(table))
+	return queryRow(ctx, db, columns, fields, query, id)
+}
+
+func (dataStore) Insert(ctx context.Context, db DBTX, table string, columns []Column, values map[string]any) (Row, error) {
+	names := []string{}
+	placeholders := []string{}
+	args := []any{}
+
+	for name, value := range values {
+		names = append(names, name)
+		args = append(args, value)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	fields := columnNames(columns)
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s) RETURNING %s;",
+		pq.QuoteIdentifier(table),
+		quoteIdentifiers(names),
+		strings.Join(placeholders, ", "),
+		quoteIdentifiers(fields),
+	)
+
+	return queryRow(ctx, db, columns, fields, query, args...)
+}
+
+func (dataStore) Update(ctx context.Context, db DBTX, table string, columns []Column, id int64, values map[string]any, replace bool) (Row, error) {
+	assignments := []string{}
+	args := []any{}
+
+	for _, column := range columns {
+		if column.Managed {
+			continue
+		}
+
+		value, ok := values[column.Name]
+		switch {
+		case ok:
+			args = append(args, value)
+			assignments = append(assignments, fmt.Sprintf("%s = $%d", pq.QuoteIdentifier(column.Name), len(args)))
+		case replace:
+			assignments = append(assignments, fmt.Sprintf("%s = DEFAULT", pq.QuoteIdentifier(column.Name)))
+		}
+	}
+
+	fields := columnNames(columns)
+	args = append(args, id)
+	query := fmt.Sprintf(
+		"UPDATE %s SET %s WHERE id = $%d RETURNING %s;",
+		pq.QuoteIdentifier(table),
+		strings.Join(assignments, ", "),
+		len(args),
+		quoteIdentifiers(fields),
+	)
+
+	return queryRow(ctx, db, columns, fields, query, args...)
+}
+
+func (dataStore) Delete(ctx context.Context, db DBTX, table string, id int64) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1;", pq.QuoteIdentifier(table))
+	result, err := db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanRow scans the current row, made of fields followed by extra
+// destinations, into a map keyed by field. specs holds the type of each field
+// and converts the values for JSON output.
+func scanRow(rows *sql.Rows, fields []string, specs []datatype.Spec, extra ...any) (Row, error) {
+	values := make([]any, len(fields))
+	pointers := make([]any, 0, len(fields)+len(extra))
+	for i := range values {
+		pointers = append(pointers, &values[i])
+	}
+	pointers = append(pointers, extra...)
+
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	row := Row{}
+	for i, field := range fields {
+		row[field] = datatype.Output(specs[i], values[i])
+	}
+	return row, nil
+}
+
+// queryRow runs a query expected to return at most one row of fields, or
+// ErrNotFound.
+func queryRow(ctx context.Context, db DBTX, columns []Column, fields []string, query string, args ...any) (Row, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+
+	byName := map[string]datatype.Spec{}
+	for _, column := range columns {
+		byName[column.Name] = column.Spec
+	}
+	specs := make([]datatype.Spec, len(fields))
+	for i, field := range fields {
+		specs[i] = byName[field]
+	}
+
+	return scanRow(rows, fields, specs)
+}
+
+func columnNames(columns []Column) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+func (dataStore) Copier(db DBTX, table string) RowWriter {
+	return &copier{db: db, table: table}
+}
+
+func (dataStore) Inserter(db DBTX, table string) RowWriter {
+	return &inserter{db: db, table: table}
+}
+
+// copier runs one COPY per run of rows with the same columns, a COPY has a
+// fixed column list. A row without columns can't be copied, it is inserted
+// with its defaults between two COPYs.
+type copier struct {
+	db      DBTX
+	table   string
+	stmt    *sql.Stmt
+	columns string
+}
+
+func (c *copier) Insert(ctx context.Context, columns []string, values []any) error {
+	if len(columns) == 0 {
+		if err := c.Flush(ctx); err != nil {
+			return err
+		}
+		_, err := c.db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", pq.QuoteIdentifier(c.table)))
+		return err
+	}
+
+	key := strings.Join(columns, "\x00")
+	if c.stmt == nil || key != c.columns {
+		if err := c.Flush(ctx); err != nil {
+			return err
+		}
+
+		stmt, err := c.db.PrepareContext(ctx, pq.CopyIn(c.table, columns...))
+		if err != nil {
+			return err
+		}
+		c.stmt, c.columns = stmt, key
+	}
+
+	_, err := c.stmt.ExecContext(ctx, values...)
+	return err
+}
+
+func (c *copier) Flush(ctx context.Context) error {
+	if c.stmt == nil {
+		return nil
+	}
+	stmt := c.stmt
+	c.stmt = nil
+
+	// the Exec without values ends the COPY and reports the rejected rows
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+type inserter struct {
+	db    DBTX
+	table string
+}
+
+func (i *inserter) Insert(ctx context.Context, columns []string, values []any) error {
+	query := fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", pq.QuoteIdentifier(i.table))
+	if len(columns) > 0 {
+		placeholders := make([]string, len(columns))
+		for n := range columns {
+			placeholders[n] = fmt.Sprintf("$%d", n+1)
+		}
+		query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			pq.QuoteIdentifier(i.table), quoteIdentifiers(columns), strings.Join(placeholders, ", "))
+	}
+
+	if _, err := i.db.ExecContext(ctx, "SAVEPOINT import_row"); err != nil {
+		return err
+	}
+	if _, err := i.db.ExecContext(ctx, query, values...); err != nil {
+		if _, rollbackErr := i.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_row"); rollbackErr != nil {
+			return rollbackErr
+		}
+		return rowError(err)
+	}
+	_, err := i.db.ExecContext(ctx, "RELEASE SAVEPOINT import_row")
+	return err
+}
+
+func (i *inserter) Flush(ctx context.Context) error {
+	return nil
+}
+
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for n, name := range names {
+		quoted[n] = pq.QuoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"wolfscream/datatype"
+	"wolfscream/rule"
+
+	"github.com/lib/pq"
+)
+
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// DataCursor points after the last row of a page. It holds the text value of
+// every sort column and of the tie breaker, the row's id or ctid for tables
+// without an id, which orders rows with equal sort values.
+type DataCursor struct {
+	Sort   string    `json:"s"`
+	Values []*string `json:"v"`
+	Tie    string    `json:"t"`
+}
+
+// DataQuery selects rows of a user defined table. Columns holds every column
+// of the table by name, Fields the columns returned.
+type DataQuery struct {
+	Table      string
+	Columns    map[string]Column
+	Fields     []string
+	Conditions []rule.Condition
+	Sort       []SortKey
+	Limit      int
+	Offset     int
+	Cursor     *DataCursor
+}
+
+// SortSpec renders the sort keys the way the sort parameter of the data API
+// spells them, e.g. "-severity,name".
+func (q *DataQuery) SortSpec() string {
+	keys := []string{}
+	for _, key := range q.Sort {
+		if key.Desc {
+			keys = append(keys, "-"+key.Column)
+		} else {
+			keys = append(keys, key.Column)
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// tieBreaker returns the column ordering rows with equal sort values and its
+// type.
+func (q *DataQuery) tieBreaker() (string, string) {
+	if column, ok := q.Columns[IdColumn]; ok && column.Managed {
+		return IdColumn, "int8"
+	}
+	return "ctid", "tid"
+}
+
+func (q *DataQuery) castType(column string) string {
+	return datatype.Cast(q.Columns[column].Spec)
+}
+
+// where renders the filter and cursor conditions, appending their arguments
+// to args.
+func (q *DataQuery) where(args *[]any, withCursor bool) string {
+	conditions := []string{}
+
+	for _, condition := range q.Conditions {
+		*args = append(*args, condition.Value)
+		placeholder := fmt.Sprintf("$%d::%s", len(*args), q.castType(condition.Column))
+
+		switch condition.Operator {
+		case rule.Equal:
+			conditions = append(conditions, fmt.Sprintf("%s = %s", pq.QuoteIdentifier(condition.Column), placeholder))
+		case rule.NotEqual:
+			conditions = append(conditions, fmt.Sprintf("%s IS DISTINCT FROM %s", pq.QuoteIdentifier(condition.Column), placeholder))
+		}
+	}
+
+	if withCursor && q.Cursor != nil {
+		conditions = append(conditions, q.keyset(0, args))
+	}
+
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// keyset renders "row comes after the cursor" for the sort keys starting at
+// i. Ascending keys sort NULLs last and descending keys NULLs first, matching
+// orderBy.
+func (q *DataQuery) keyset(i int, args *[]any) string {
+	if i == len(q.Sort) {
+		tieBreaker, tieBreakerType := q.tieBreaker()
+		*args = append(*args, q.Cursor.Tie)
+		return fmt.Sprintf("%s > $%d::%s", tieBreaker, len(*args), tieBreakerType)
+	}
+
+	key := q.Sort[i]
+	column := pq.QuoteIdentifier(key.Column)
+	rest := q.keyset(i+1, args)
+	value := q.Cursor.Values[i]
+
+	if value == nil {
+		if key.Desc {
+			return fmt.Sprintf("(%s IS NOT NULL OR (%s IS NULL AND %s))", column, column, rest)
+		}
+		return fmt.Sprintf("(%s IS NULL AND %s)", column, rest)
+	}
+
+	*args = append(*args, *value)
+	placeholder := fmt.Sprintf("$%d::%s", len(*args), q.castType(key.Column))
+
+	if key.Desc {
+		return fmt.Sprintf("(%s < %s OR (%s = %s AND %s))", column, placeholder, column, placeholder, rest)
+	}
+	return fmt.Sprintf("(%s > %s OR %s IS NULL OR (%s = %s AND %s))", column, placeholder, column, column, placeholder, rest)
+}
+
+func (q *DataQuery) orderBy() string {
+	keys := []string{}
+	for _, key := range q.Sort {
+		if key.Desc {
+			keys = append(keys, pq.QuoteIdentifier(key.Column)+" DESC NULLS FIRST")
+		} else {
+			keys = append(keys, pq.QuoteIdentifier(key.Column)+" ASC NULLS LAST")
+		}
+	}
+	tieBreaker, _ := q.tieBreaker()
+	keys = append(keys, tieBreaker+" ASC")
+	return " ORDER BY " + strings.Join(keys, ", ")
+}
+
+// selectQuery returns the page query. Besides the requested fields it selects
+// the text value of the sort columns and the tie breaker, used to build the
+// cursor of the next page.
+// One row more than the limit is fetched to know whether there is a next page.
+func (q *DataQuery) selectQuery() (string, []any) {
+	args := []any{}
+
+	selected := []string{}
+	if len(q.Fields) > 0 {
+		selected = append(selected, quoteIdentifiers(q.Fields))
+	}
+	for i, key := range q.Sort {
+		selected = append(selected, fmt.Sprintf("%s::text AS __cursor_%d", pq.QuoteIdentifier(key.Column), i))
+	}
+	tieBreaker, _ := q.tieBreaker()
+	selected = append(selected, tieBreaker+"::text AS __cursor_tie")
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selected, ", "), pq.QuoteIdentifier(q.Table))
+	query += q.where(&args, true)
+	query += q.orderBy()
+
+	args = append(args, q.Limit+1)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	if q.Cursor == nil && q.Offset > 0 {
+		args = append(args, q.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	return query, args
+}
+
+// exportQuery selects the requested fields of every row matching the filter,
+// without pagination.
+func (q *DataQuery) exportQuery() (string, []any) {
+	args := []any{}
+	query := fmt.Sprintf("SELECT %s FROM %s", quoteIdentifiers(q.Fields), pq.QuoteIdentifier(q.Table))
+	query += q.where(&args, false)
+	query += q.orderBy()
+	return query, args
+}
+
+// countQuery counts every row matching the filter, ignoring pagination.
+func (q *DataQuery) countQuery() (string, []any) {
+	args := []any{}
+	query := fmt.Sprintf("SELECT count(*) FROM %s", pq.QuoteIdentifier(q.Table))
+	query += q.where(&args, false)
+	return query, args
+}
+
+// fieldSpecs returns the type of each field.
+func (q *DataQuery) fieldSpecs() []datatype.Spec {
+	specs := make([]datatype.Spec, len(q.Fields))
+	for i, field := range q.Fields {
+		specs[i] = q.Columns[field].Spec
+	}
+	return specs
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// cleanupTimeout bounds the cleanup after a failed index build.
+const cleanupTimeout = 30 * time.Second
+
+// Index is a registered index. Valid is false while a concurrent build is
+// running or after it failed.
+type Index struct {
+	Name      string    `json:"name"`
+	Method    string    `json:"method"`
+	Unique    bool      `json:"unique"`
+	Columns   []string  `json:"columns"`
+	Valid     bool      `json:"valid"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IndexStore builds the indexes of user defined tables and keeps
+// user_defined_index in step with them. Create and Drop use CONCURRENTLY,
+// so they can't run inside a transaction.
+type IndexStore interface {
+	List(ctx context.Context, db DBTX, table string) ([]Index, error)
+	// Get returns ErrNotFound when the table has no such index.
+	Get(ctx context.Context, db DBTX, table string, name string) (*Index, error)
+	// Create registers index and builds it. The metadata row is written
+	// first so the name is reserved before the long build starts. When the
+	// build fails the invalid index it leaves behind and the metadata row
+	// are removed.
+	Create(ctx context.Context, db DBTX, table string, index *Index) error
+	// Drop drops an index and unregisters it.
+	Drop(ctx context.Context, db DBTX, name string) error
+}
+
+type indexStore struct{}
+
+const listIndexesQuery = `
+	SELECT
+		udi.name,
+		udi.method,
+		udi.is_unique,
+		udi.columns,
+		COALESCE(pi.indisvalid, false),
+		udi.created_at
+	FROM user_defined_index udi
+		JOIN user_defined_table udt ON udi.user_defined_table_id = udt.id
+		LEFT JOIN pg_class pc ON pc.relname = udi.name AND pc.relnamespace = current_schema()::regnamespace
+		LEFT JOIN pg_index pi ON pi.indexrelid = pc.oid
+	WHERE udt.name = $1`
+
+func (indexStore) List(ctx context.Context, db DBTX, table string) ([]Index, error) {
+	rows, err := db.QueryContext(ctx, listIndexesQuery+" ORDER BY udi.id ASC", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := []Index{}
+	for rows.Next() {
+		index, err := scanIndex(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+		indexes = append(indexes, *index)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return indexes, nil
+}
+
+func (indexStore) Get(ctx context.Context, db DBTX, table string, name string) (*Index, error) {
+	index, err := scanIndex(db.QueryRowContext(ctx, listIndexesQuery+" AND udi.name = $2", table, name))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return index, nil
+}
+
+func scanIndex(row interface{ Scan(...any) error }) (*Index, error) {
+	var index Index
+	if err := row.Scan(&index.Name, &index.Method, &index.Unique, (*pq.StringArray)(&index.Columns), &index.Valid, &index.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+func (indexStore) Create(ctx context.Context, db DBTX, table string, index *Index) error {
+	var indexId int
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO user_defined_index (user_defined_table_id, name, method, is_unique, columns)
+		VALUES ((SELECT id FROM user_defined_table WHERE name = $1), $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, table, index.Name, index.Method, index.Unique, pq.StringArray(index.Columns)).Scan(&indexId, &index.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to register index: %w", err)
+	}
+
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+
+	columns := make([]string, len(index.Columns))
+	for i, column := range index.Columns {
+		columns[i] = pq.QuoteIdentifier(column)
+	}
+
+	query := fmt.Sprintf(
+		"CREATE %sINDEX CONCURRENTLY %s ON %s USING %s (%s)",
+		unique, pq.QuoteIdentifier(index.Name), pq.QuoteIdentifier(table), index.Method, strings.Join(columns, ", "),
+	)
+
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		// the build often failed because ctx was canceled, the cleanup
+		// must still run
+		cleanup, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+		defer cancel()
+
+		if dropErr := dropInvalidIndex(cleanup, db, table, index.Name); dropErr != nil {
+			slog.ErrorContext(cleanup, "failed to drop invalid index", "index", index.Name, "error", dropErr)
+		}
+		if _, deleteErr := db.ExecContext(cleanup, "DELETE FROM user_defined_index WHERE id = $1", indexId); deleteErr != nil {
+			slog.ErrorContext(cleanup, "failed to unregister index", "index", index.Name, "error", deleteErr)
+		}
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	index.Valid = true
+	return nil
+}
+
+func (indexStore) Drop(ctx context.Context, db DBTX, name string) error {
+	if err := dropIndexConcurrently(ctx, db, name); err != nil {
+		return fmt.Errorf("failed to drop index: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM user_defined_index WHERE name = $1", name); err != nil {
+		return fmt.Errorf("failed to unregister index: %w", err)
+	}
+	return nil
+}
+
+// dropIndexConcurrently drops an index without blocking writes to its table.
+func dropIndexConcurrently(ctx context.Context, db DBTX, name string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s", pq.QuoteIdentifier(name)))
+	return err
+}
+
+// dropInvalidIndex drops the index left by a failed concurrent build. The
+// build may also have failed because the name was taken, so only an invalid
+// index of the table is dropped.
+func dropInvalidIndex(ctx context.Context, db DBTX, table string, name string) error {
+	var invalid bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM pg_index pi
+				JOIN pg_class pc ON pc.oid = pi.indexrelid
+			WHERE pc.relname = $1
+				AND pc.relnamespace = current_schema()::regnamespace
+				AND pi.indrelid = $2::regclass
+				AND NOT pi.indisvalid
+		)
+	`, name, pq.QuoteIdentifier(table)).Scan(&invalid)
+	if err != nil || !invalid {
+		return err
+	}
+	return dropIndexConcurrently(ctx, db, name)
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"wolfscream/models"
+
+	"github.com/lib/pq"
+)
+
+// LogLevels are the levels of scheduled_message_log, from the least severe.
+var LogLevels = []string{"INFO", "WARN", "ERROR"}
+
+// LogFilter selects the logs of a scheduled message. Zero fields don't
+// filter.
+type LogFilter struct {
+	ScheduledMessageId int
+	Levels             []string
+	RunId              string
+	// Since is inclusive, Until exclusive.
+	Since *time.Time
+	Until *time.Time
+	// Search matches a substring of the text, ignoring case.
+	Search string
+	// After continues from the last log of the previous page.
+	After *LogCursor
+	Limit int
+}
+
+// LogCursor is the position of a log in the newest first order. CreatedAt
+// is the text value of the column so the position round-trips exactly.
+type LogCursor struct {
+	CreatedAt string `json:"c"`
+	Id        string `json:"i"`
+}
+
+type LogStore interface {
+	// Write stores a log line of a scheduled message and returns it with
+	// its id and time. runId is the run that wrote it, or "".
+	Write(ctx context.Context, db DBTX, scheduledMessageId int, runId string, level string, text string) (*models.Log, error)
+	// List returns the logs matching filter, newest first, with the cursor
+	// of each.
+	List(ctx context.Context, db DBTX, filter LogFilter) ([]models.Log, []LogCursor, error)
+	// Prune deletes the logs of level written before cutoff, in batches so
+	// writers aren't blocked for long, and returns how many it deleted.
+	Prune(ctx context.Context, db DBTX, level string, cutoff time.Time) (int64, error)
+}
+
+type logStore struct{}
+
+func (logStore) Write(ctx context.Context, db DBTX, scheduledMessageId int, runId string, level string, text string) (*models.Log, error) {
+	entry := models.Log{Text: text, Level: level}
+	if runId != "" {
+		entry.RunId = &runId
+	}
+
+	err := db.QueryRowContext(ctx,
+		"INSERT INTO scheduled_message_log (scheduled_message_id, run_id, text, level) VALUES ($1, $2, $3, $4) RETURNING id, created_at;",
+		scheduledMessageId, entry.RunId, text, level,
+	).Scan(&entry.Id, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (logStore) List(ctx context.Context, db DBTX, filter LogFilter) ([]models.Log, []LogCursor, error) {
+	args := []any{filter.ScheduledMessageId}
+	conditions := []string{"scheduled_message_id = $1"}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.Levels) > 0 {
+		conditions = append(conditions, fmt.Sprintf("level::text = ANY(%s)", arg(pq.Array(filter.Levels))))
+	}
+	if filter.RunId != "" {
+		conditions = append(conditions, fmt.Sprintf("run_id = %s::uuid", arg(filter.RunId)))
+	}
+	// created_at is compared as timestamptz, in the time zone of the session
+	if filter.Since != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at >= %s::timestamptz", arg(*filter.Since)))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at < %s::timestamptz", arg(*filter.Until)))
+	}
+	if filter.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("text ILIKE '%%' || %s || '%%'", arg(escapeLike(filter.Search))))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf(
+			"(created_at, id) < (%s::timestamp, %s::int)", arg(filter.After.CreatedAt), arg(filter.After.Id),
+		))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, text, level, created_at, run_id, created_at::text
+		FROM scheduled_message_log
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT %s
+	`, strings.Join(conditions, " AND "), arg(filter.Limit))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []models.Log{}
+	cursors := []LogCursor{}
+	for rows.Next() {
+		var log models.Log
+		var cursor LogCursor
+		if err := rows.Scan(&log.Id, &log.Text, &log.Level, &log.CreatedAt, &log.RunId, &cursor.CreatedAt); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan log: %w", err)
+		}
+		cursor.Id = log.Id
+		logs = append(logs, log)
+		cursors = append(cursors, cursor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return logs, cursors, nil
+}
+
+// pruneBatch is how many logs a single DELETE removes.
+const pruneBatch = 5000
+
+func (logStore) Prune(ctx context.Context, db DBTX, level string, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		result, err := db.ExecContext(ctx, `
+			DELETE FROM scheduled_message_log
+			WHERE id IN (
+				SELECT id FROM scheduled_message_log
+				WHERE level = $1 AND created_at < $2::timestamptz
+				LIMIT $3
+			)
+		`, level, cutoff, pruneBatch)
+		if err != nil {
+			return total, fmt.Errorf("failed to prune %s logs: %w", level, err)
+		}
+
+		deleted, _ := result.RowsAffected()
+		total += deleted
+		if deleted < pruneBatch {
+			return total, nil
+		}
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"wolfscream/models"
+)
+
+type PlatformStore interface {
+	List(ctx context.Context, db DBTX) ([]models.Platform, error)
+	// Get returns ErrNotFound when the platform doesn't exist.
+	Get(ctx context.Context, db DBTX, id int) (*models.Platform, error)
+}
+
+type platformStore struct{}
+
+func (platformStore) List(ctx context.Context, db DBTX) ([]models.Platform, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, image_url, created_at FROM communication_platform;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query platforms: %w", err)
+	}
+	defer rows.Close()
+
+	platforms := []models.Platform{}
+	for rows.Next() {
+		var platform models.Platform
+		if err := rows.Scan(&platform.Id, &platform.Name, &platform.ImageUrl, &platform.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan platform: %w", err)
+		}
+		platforms = append(platforms, platform)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return platforms, nil
+}
+
+func (platformStore) Get(ctx context.Context, db DBTX, id int) (*models.Platform, error) {
+	var platform models.Platform
+	err := db.QueryRowContext(ctx, "SELECT id, name, image_url, created_at FROM communication_platform WHERE id = $1", id).
+		Scan(&platform.Id, &platform.Name, &platform.ImageUrl, &platform.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &platform, nil
+}
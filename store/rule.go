@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"wolfscream/models"
+)
+
+type RuleStore interface {
+	List(ctx context.Context, db DBTX) ([]models.Rule, error)
+	Create(ctx context.Context, db DBTX, name string, rule string, description *string) error
+}
+
+type ruleStore struct{}
+
+func (ruleStore) List(ctx context.Context, db DBTX) ([]models.Rule, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, description, rule, created_at FROM scheduled_message_rule ORDER BY created_at ASC;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.Rule{}
+	for rows.Next() {
+		var rule models.Rule
+		if err := rows.Scan(&rule.Id, &rule.Name, &rule.Description, &rule.Text, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (ruleStore) Create(ctx context.Context, db DBTX, name string, rule string, description *string) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO scheduled_message_rule(name, rule, description) VALUES ($1, $2, $3);", name, rule, description)
+	return err
+}
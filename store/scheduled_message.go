@@ -0,0 +1,358 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"wolfscream/models"
+)
+
+// ScheduledMessageSummary is a scheduled message with the names of its
+// table and platform. RunningId is the cron entry while it runs.
+type ScheduledMessageSummary struct {
+	Id               int
+	Name             string
+	Description      string
+	ScheduleType     string
+	Table            string
+	Platform         string
+	PlatformImageUrl string
+	RunningId        *int
+	SuccessCount     int
+	FailedCount      int
+}
+
+// ScheduledMessageDefinition is what a scheduled message needs to run.
+type ScheduledMessageDefinition struct {
+	Id           int
+	Name         string
+	ScheduleType string
+	Rule         string
+	Message      string
+	Table        string
+	Platform     string
+}
+
+type Interval struct {
+	Value int
+	Unit  string
+}
+
+// Cron holds the fields of a cron schedule, nil fields match every value.
+type Cron struct {
+	Minute     *string
+	Hour       *string
+	DayOfMonth *string
+	Month      *string
+	DayOfWeek  *string
+}
+
+type StateChange struct {
+	Id        int       `json:"id"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduledMessageStore interface {
+	List(ctx context.Context, db DBTX) ([]ScheduledMessageSummary, error)
+	// Get returns the summary with the execution counts, or ErrNotFound.
+	Get(ctx context.Context, db DBTX, name string) (*ScheduledMessageSummary, error)
+	// Definition returns ErrNotFound when the scheduled message doesn't
+	// exist.
+	Definition(ctx context.Context, db DBTX, name string) (*ScheduledMessageDefinition, error)
+	Create(ctx context.Context, db DBTX, message models.ScheduledMessage) (int, error)
+
+	SetDiscordConfig(ctx context.Context, db DBTX, id int, channelId string) error
+	DiscordConfig(ctx context.Context, db DBTX, id int) (*models.DiscordConfig, error)
+	SetInterval(ctx context.Context, db DBTX, id int, interval Interval) error
+	Interval(ctx context.Context, db DBTX, id int) (*Interval, error)
+	SetCron(ctx context.Context, db DBTX, id int, cron Cron) error
+	Cron(ctx context.Context, db DBTX, id int) (*Cron, error)
+
+	// Start records that the scheduled message runs as cron entry entryId.
+	Start(ctx context.Context, db DBTX, id int, entryId int) error
+	// Running returns the id of the scheduled message and its cron entry,
+	// nil when it isn't running. It returns ErrNotFound when the scheduled
+	// message doesn't exist.
+	Running(ctx context.Context, db DBTX, name string) (int, *int, error)
+	Stop(ctx context.Context, db DBTX, id int, entryId int) error
+	// StateHistory returns the last 100 state changes, oldest first.
+	StateHistory(ctx context.Context, db DBTX, name string) ([]StateChange, error)
+
+	RecordExecution(ctx context.Context, db DBTX, id int, status string) error
+}
+
+type scheduledMessageStore struct{}
+
+func (scheduledMessageStore) List(ctx context.Context, db DBTX) ([]ScheduledMessageSummary, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			sm.id,
+			sm.name,
+			sm.description,
+			sm.schedule_type,
+			t.name,
+			p.name,
+			p.image_url,
+			rsm.id
+		FROM scheduled_message sm
+			JOIN communication_platform p ON sm.communication_platform_id = p.id
+			JOIN user_defined_table t on sm.user_defined_table_id = t.id
+			LEFT JOIN running_scheduled_message rsm ON sm.id = rsm.scheduled_message_id
+		ORDER BY sm.created_at DESC;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []ScheduledMessageSummary{}
+	for rows.Next() {
+		var m ScheduledMessageSummary
+		if err := rows.Scan(&m.Id, &m.Name, &m.Description, &m.ScheduleType, &m.Table, &m.Platform, &m.PlatformImageUrl, &m.RunningId); err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (scheduledMessageStore) Get(ctx context.Context, db DBTX, name string) (*ScheduledMessageSummary, error) {
+	var m ScheduledMessageSummary
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			sm.id,
+			sm.name,
+			sm.description,
+			sm.schedule_type,
+			t.name AS table_name,
+			p.name AS platform_name,
+			p.image_url,
+			rsm.id AS running_id,
+			smeh.success_count,
+			smeh.failed_count
+		FROM scheduled_message sm
+		JOIN communication_platform p
+			ON sm.communication_platform_id = p.id
+		JOIN user_defined_table t
+			ON sm.user_defined_table_id = t.id
+		LEFT JOIN running_scheduled_message rsm
+			ON sm.id = rsm.scheduled_message_id
+
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) FILTER (WHERE status = 'success') AS success_count,
+				COUNT(*) FILTER (WHERE status = 'failed')  AS failed_count
+			FROM scheduled_message_execution_history
+			WHERE scheduled_message_id = sm.id
+		) smeh ON true
+		WHERE sm.name = $1;
+	`, name).Scan(
+		&m.Id,
+		&m.Name,
+		&m.Description,
+		&m.ScheduleType,
+		&m.Table,
+		&m.Platform,
+		&m.PlatformImageUrl,
+		&m.RunningId,
+		&m.SuccessCount,
+		&m.FailedCount,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &m, nil
+}
+
+func (scheduledMessageStore) Definition(ctx context.Context, db DBTX, name string) (*ScheduledMessageDefinition, error) {
+	var d ScheduledMessageDefinition
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			sm.id,
+			sm.name,
+			sm.schedule_type,
+			sm.rule,
+			sm.message,
+			t.name,
+			p.name
+		FROM scheduled_message sm
+			JOIN user_defined_table t ON sm.user_defined_table_id = t.id
+			JOIN communication_platform p ON sm.communication_platform_id = p.id
+		WHERE sm.name = $1
+	`, name).Scan(&d.Id, &d.Name, &d.ScheduleType, &d.Rule, &d.Message, &d.Table, &d.Platform)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &d, nil
+}
+
+func (scheduledMessageStore) Create(ctx context.Context, db DBTX, message models.ScheduledMessage) (int, error) {
+	var id int
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO scheduled_message (
+			name, description, user_defined_table_id, message, rule, communication_platform_id, schedule_type
+		)
+		VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)
+		RETURNING
+			id;
+	`,
+		message.Name,
+		message.Description,
+		message.TableId,
+		message.Message,
+		message.Rule,
+		message.PlatformId,
+		message.ScheduleType,
+	).Scan(&id)
+	return id, err
+}
+
+func (scheduledMessageStore) SetDiscordConfig(ctx context.Context, db DBTX, id int, channelId string) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO scheduled_message_discord_config(channel_id, scheduled_message_id) VALUES ($1, $2)", channelId, id)
+	return err
+}
+
+func (scheduledMessageStore) DiscordConfig(ctx context.Context, db DBTX, id int) (*models.DiscordConfig, error) {
+	var config models.DiscordConfig
+	err := db.QueryRowContext(ctx, `
+		SELECT id, channel_id, scheduled_message_id, created_at FROM scheduled_message_discord_config WHERE scheduled_message_id = $1
+	`, id).Scan(&config.Id, &config.ChannelId, &config.ScheduledMessageId, &config.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &config, nil
+}
+
+func (scheduledMessageStore) SetInterval(ctx context.Context, db DBTX, id int, interval Interval) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO scheduled_message_interval(value, unit, scheduled_message_id) VALUES ($1, $2, $3);", interval.Value, interval.Unit, id)
+	return err
+}
+
+func (scheduledMessageStore) Interval(ctx context.Context, db DBTX, id int) (*Interval, error) {
+	var interval Interval
+	err := db.QueryRowContext(ctx, `
+		SELECT value, unit FROM scheduled_message_interval
+		WHERE scheduled_message_id = $1
+	`, id).Scan(&interval.Value, &interval.Unit)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &interval, nil
+}
+
+func (scheduledMessageStore) SetCron(ctx context.Context, db DBTX, id int, cron Cron) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO scheduled_message_cron(minute, hour, day_of_month, month, day_of_week, scheduled_message_id) VALUES ($1, $2, $3, $4, $5, $6);",
+		cron.Minute, cron.Hour, cron.DayOfMonth, cron.Month, cron.DayOfWeek, id,
+	)
+	return err
+}
+
+func (scheduledMessageStore) Cron(ctx context.Context, db DBTX, id int) (*Cron, error) {
+	var cron Cron
+	err := db.QueryRowContext(ctx, `
+		SELECT minute, hour, day_of_month, month, day_of_week FROM scheduled_message_cron WHERE scheduled_message_id = $1
+	`, id).Scan(&cron.Minute, &cron.Hour, &cron.DayOfMonth, &cron.Month, &cron.DayOfWeek)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &cron, nil
+}
+
+func (scheduledMessageStore) Start(ctx context.Context, db DBTX, id int, entryId int) error {
+	if _, err := db.ExecContext(ctx, "INSERT INTO scheduled_message_state_history(scheduled_message_id, state) VALUES ($1, $2);", id, "started"); err != nil {
+		return fmt.Errorf("failed to record state: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "INSERT INTO running_scheduled_message(id, scheduled_message_id) VALUES ($1, $2);", entryId, id); err != nil {
+		return fmt.Errorf("failed to record running scheduled message: %w", err)
+	}
+
+	return nil
+}
+
+func (scheduledMessageStore) Running(ctx context.Context, db DBTX, name string) (int, *int, error) {
+	var (
+		id      int
+		entryId *int
+	)
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			sm.id,
+			rsm.id
+		FROM scheduled_message sm
+		LEFT JOIN running_scheduled_message rsm ON sm.id = rsm.scheduled_message_id
+		WHERE name = $1
+	`, name).Scan(&id, &entryId)
+	if err != nil {
+		return 0, nil, notFound(err)
+	}
+	return id, entryId, nil
+}
+
+func (scheduledMessageStore) Stop(ctx context.Context, db DBTX, id int, entryId int) error {
+	if _, err := db.ExecContext(ctx, "DELETE FROM running_scheduled_message WHERE id = $1", entryId); err != nil {
+		return fmt.Errorf("failed to remove running scheduled message: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "INSERT INTO scheduled_message_state_history(scheduled_message_id, state) VALUES ($1, $2);", id, "stopped"); err != nil {
+		return fmt.Errorf("failed to record state: %w", err)
+	}
+
+	return nil
+}
+
+func (scheduledMessageStore) StateHistory(ctx context.Context, db DBTX, name string) ([]StateChange, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			id,
+			state,
+			created_at
+		FROM (
+			SELECT
+				smsh.id,
+				smsh.state,
+				smsh.created_at
+			FROM scheduled_message_state_history smsh
+			LEFT JOIN scheduled_message sm
+				ON smsh.scheduled_message_id = sm.id
+			WHERE sm.name = $1
+			ORDER BY smsh.created_at DESC
+			LIMIT 100
+		) latest
+		ORDER BY created_at ASC;
+	`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query state history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []StateChange{}
+	for rows.Next() {
+		var change StateChange
+		if err := rows.Scan(&change.Id, &change.State, &change.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan state change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return changes, nil
+}
+
+func (scheduledMessageStore) RecordExecution(ctx context.Context, db DBTX, id int, status string) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO scheduled_message_execution_history(scheduled_message_id, status) VALUES ($1, $2);", id, status)
+	return err
+}
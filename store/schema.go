@@ -0,0 +1,389 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"wolfscream/datatype"
+	"wolfscream/drift"
+
+	"github.com/lib/pq"
+)
+
+// catalogTypes maps the udt_name of information_schema.columns to the column
+// types of datatype. Other types are reported as they are and can't be
+// adopted.
+var catalogTypes = map[string]string{
+	"int4": "int4", "int8": "int8", "float4": "float4", "float8": "float8", "numeric": "numeric",
+	"varchar": "varchar", "text": "text", "bool": "bool",
+	"timestamptz": "timestamptz", "date": "date",
+	"jsonb": "jsonb", "uuid": "uuid", "inet": "inet",
+	"_text": "text[]",
+}
+
+// SchemaStore changes the user defined tables themselves and reads the
+// catalog. The checks run before a change count the rows that would break
+// it, so callers can report them instead of a bare Postgres error.
+type SchemaStore interface {
+	// Catalog returns the tables of the current schema with their columns.
+	Catalog(ctx context.Context, db DBTX) ([]drift.Table, error)
+	// LockMetadata keeps concurrent metadata changes out until the
+	// transaction db ends.
+	LockMetadata(ctx context.Context, db DBTX) error
+	// LockTable keeps writers out of table until the transaction db ends.
+	LockTable(ctx context.Context, db DBTX, table string) error
+
+	CreateEnum(ctx context.Context, db DBTX, spec datatype.Spec) error
+	DropEnum(ctx context.Context, db DBTX, name string) error
+	RenameEnum(ctx context.Context, db DBTX, name string, newName string) error
+
+	// CheckConversion counts the rows of table and the values of column
+	// whose text isn't valid for targetType, with up to samples distinct
+	// invalid values. It needs Postgres 16.
+	CheckConversion(ctx context.Context, db DBTX, table string, column string, targetType string, samples int) (rows int, invalid int, values []string, err error)
+	// ConvertColumn converts column to targetType through the text of its
+	// values. With invalidToNull the values that don't convert become NULL.
+	ConvertColumn(ctx context.Context, db DBTX, table string, column string, targetType string, invalidToNull bool) error
+
+	// SetDefault sets the default of column to a SQL literal, nil drops it.
+	SetDefault(ctx context.Context, db DBTX, table string, column string, literal *string) error
+	SetNullable(ctx context.Context, db DBTX, table string, column string, nullable bool) error
+	// Backfill writes a SQL literal to the rows where column is NULL.
+	Backfill(ctx context.Context, db DBTX, table string, column string, literal string) error
+	// CountNulls counts the rows where column is NULL.
+	CountNulls(ctx context.Context, db DBTX, table string, column string) (int, error)
+	// Duplicates returns up to limit values of column held by more than one
+	// row, and how many rows hold them.
+	Duplicates(ctx context.Context, db DBTX, table string, column string, limit int) (values []string, rows int, err error)
+	// CountViolations counts the rows not satisfying a SQL expression.
+	CountViolations(ctx context.Context, db DBTX, table string, expression string) (int, error)
+	// CountDangling counts the rows where column holds an id missing from
+	// target.
+	CountDangling(ctx context.Context, db DBTX, table string, column string, target string) (int, error)
+
+	// DropColumnConstraints drops the constraints of kind contype (u, c or
+	// f) on column alone. They are looked up in the catalog, so they are
+	// found after the table or the column was renamed.
+	DropColumnConstraints(ctx context.Context, db DBTX, table string, column string, contype string) error
+	AddUnique(ctx context.Context, db DBTX, table string, column string, name string) error
+	// AddCheck adds a check constraint made of a SQL expression.
+	AddCheck(ctx context.Context, db DBTX, table string, name string, expression string) error
+	// AddForeignKey makes column reference the id of target. onDelete is
+	// the SQL action, e.g. "SET NULL".
+	AddForeignKey(ctx context.Context, db DBTX, table string, column string, name string, target string, onDelete string) error
+}
+
+type schemaStore struct{}
+
+func (schemaStore) Catalog(ctx context.Context, db DBTX) ([]drift.Table, error) {
+	enums := map[string][]string{}
+	rows, err := db.QueryContext(ctx, `
+		SELECT t.typname, array_agg(e.enumlabel ORDER BY e.enumsortorder)
+		FROM pg_type t
+			JOIN pg_enum e ON e.enumtypid = t.oid
+		WHERE t.typnamespace = current_schema()::regnamespace
+		GROUP BY t.typname
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query enum types: %w", err)
+	}
+	for rows.Next() {
+		var (
+			name   string
+			values []string
+		)
+		if err := rows.Scan(&name, (*pq.StringArray)(&values)); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan enum type: %w", err)
+		}
+		enums[name] = values
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT
+			t.table_name,
+			c.column_name,
+			c.udt_name,
+			c.character_maximum_length,
+			c.numeric_precision,
+			c.numeric_scale,
+			c.is_nullable = 'YES'
+		FROM information_schema.tables t
+			LEFT JOIN information_schema.columns c ON c.table_schema = t.table_schema AND c.table_name = t.table_name
+		WHERE t.table_schema = current_schema() AND t.table_type = 'BASE TABLE'
+		ORDER BY t.table_name, c.ordinal_position
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query catalog: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []drift.Table{}
+	for rows.Next() {
+		var (
+			table     string
+			name      sql.NullString
+			udtName   sql.NullString
+			length    *int
+			precision *int
+			scale     *int
+			nullable  sql.NullBool
+		)
+		if err := rows.Scan(&table, &name, &udtName, &length, &precision, &scale, &nullable); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+
+		if len(tables) == 0 || tables[len(tables)-1].Name != table {
+			tables = append(tables, drift.Table{Name: table, Columns: []drift.Column{}})
+		}
+		if !name.Valid {
+			continue
+		}
+
+		column := drift.Column{Name: name.String, Nullable: nullable.Bool}
+		switch {
+		case catalogTypes[udtName.String] != "":
+			column.Spec.Type = catalogTypes[udtName.String]
+		case enums[udtName.String] != nil:
+			column.Spec = datatype.Spec{Type: "enum", EnumType: udtName.String, EnumValues: enums[udtName.String]}
+		default:
+			column.Spec.Type = udtName.String
+		}
+		switch column.Spec.Type {
+		case "varchar":
+			column.Spec.Length = length
+		case "numeric":
+			column.Spec.Precision, column.Spec.Scale = precision, scale
+		}
+
+		tables[len(tables)-1].Columns = append(tables[len(tables)-1].Columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return tables, nil
+}
+
+func (schemaStore) LockMetadata(ctx context.Context, db DBTX) error {
+	_, err := db.ExecContext(ctx, "LOCK TABLE user_defined_table, user_defined_column IN SHARE ROW EXCLUSIVE MODE")
+	return err
+}
+
+func (schemaStore) LockTable(ctx context.Context, db DBTX, table string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("LOCK TABLE %s IN SHARE ROW EXCLUSIVE MODE", pq.QuoteIdentifier(table)))
+	return err
+}
+
+func (schemaStore) CreateEnum(ctx context.Context, db DBTX, spec datatype.Spec) error {
+	_, err := db.ExecContext(ctx, datatype.CreateEnum(spec))
+	return err
+}
+
+func (schemaStore) DropEnum(ctx context.Context, db DBTX, name string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("DROP TYPE IF EXISTS %s", pq.QuoteIdentifier(name)))
+	return err
+}
+
+func (schemaStore) RenameEnum(ctx context.Context, db DBTX, name string, newName string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TYPE %s RENAME TO %s", pq.QuoteIdentifier(name), pq.QuoteIdentifier(newName)))
+	return err
+}
+
+// validInput tests whether the text of column is a valid targetType.
+func validInput(column string, targetType string) string {
+	return fmt.Sprintf("pg_input_is_valid(%s::text, %s)", pq.QuoteIdentifier(column), pq.QuoteLiteral(targetType))
+}
+
+func (schemaStore) CheckConversion(ctx context.Context, db DBTX, table string, column string, targetType string, samples int) (int, int, []string, error) {
+	quotedTable := pq.QuoteIdentifier(table)
+	quotedColumn := pq.QuoteIdentifier(column)
+	valid := validInput(column, targetType)
+
+	var total, invalid int
+	err := db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT count(*), count(*) FILTER (WHERE %s IS NOT NULL AND NOT %s) FROM %s",
+		quotedColumn, valid, quotedTable,
+	)).Scan(&total, &invalid)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	values := []string{}
+	if invalid == 0 {
+		return total, invalid, values, nil
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT DISTINCT %s::text FROM %s WHERE %s IS NOT NULL AND NOT %s LIMIT %d",
+		quotedColumn, quotedTable, quotedColumn, valid, samples,
+	))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sample string
+		if err := rows.Scan(&sample); err != nil {
+			return 0, 0, nil, err
+		}
+		values = append(values, sample)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, nil, err
+	}
+
+	return total, invalid, values, nil
+}
+
+func (schemaStore) ConvertColumn(ctx context.Context, db DBTX, table string, column string, targetType string, invalidToNull bool) error {
+	quotedColumn := pq.QuoteIdentifier(column)
+
+	using := fmt.Sprintf("%s::text::%s", quotedColumn, targetType)
+	if invalidToNull {
+		using = fmt.Sprintf("CASE WHEN %s THEN %s END", validInput(column, targetType), using)
+	}
+
+	_, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s", pq.QuoteIdentifier(table), quotedColumn, targetType, using))
+	return err
+}
+
+func (schemaStore) SetDefault(ctx context.Context, db DBTX, table string, column string, literal *string) error {
+	action := "DROP DEFAULT"
+	if literal != nil {
+		action = "SET DEFAULT " + *literal
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", pq.QuoteIdentifier(table), pq.QuoteIdentifier(column), action))
+	return err
+}
+
+func (schemaStore) SetNullable(ctx context.Context, db DBTX, table string, column string, nullable bool) error {
+	action := "SET NOT NULL"
+	if nullable {
+		action = "DROP NOT NULL"
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", pq.QuoteIdentifier(table), pq.QuoteIdentifier(column), action))
+	return err
+}
+
+func (schemaStore) Backfill(ctx context.Context, db DBTX, table string, column string, literal string) error {
+	quotedColumn := pq.QuoteIdentifier(column)
+	_, err := db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL", pq.QuoteIdentifier(table), quotedColumn, literal, quotedColumn))
+	return err
+}
+
+func countRows(ctx context.Context, db DBTX, table string, condition string) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT count(*) FROM %s WHERE %s", pq.QuoteIdentifier(table), condition)).Scan(&n)
+	return n, err
+}
+
+func (schemaStore) CountNulls(ctx context.Context, db DBTX, table string, column string) (int, error) {
+	return countRows(ctx, db, table, pq.QuoteIdentifier(column)+" IS NULL")
+}
+
+func (schemaStore) Duplicates(ctx context.Context, db DBTX, table string, column string, limit int) ([]string, int, error) {
+	quotedColumn := pq.QuoteIdentifier(column)
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT %s::text, count(*) FROM %s WHERE %s IS NOT NULL GROUP BY 1 HAVING count(*) > 1 ORDER BY 2 DESC LIMIT %d",
+		quotedColumn, pq.QuoteIdentifier(table), quotedColumn, limit,
+	))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		values []string
+		total  int
+	)
+	for rows.Next() {
+		var (
+			value string
+			n     int
+		)
+		if err := rows.Scan(&value, &n); err != nil {
+			return nil, 0, err
+		}
+		values = append(values, value)
+		total += n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return values, total, nil
+}
+
+func (schemaStore) CountViolations(ctx context.Context, db DBTX, table string, expression string) (int, error) {
+	return countRows(ctx, db, table, fmt.Sprintf("NOT (%s)", expression))
+}
+
+func (schemaStore) CountDangling(ctx context.Context, db DBTX, table string, column string, target string) (int, error) {
+	quotedColumn := pq.QuoteIdentifier(column)
+	return countRows(ctx, db, table, fmt.Sprintf(
+		"%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %s target WHERE target.%s = %s.%s)",
+		quotedColumn, pq.QuoteIdentifier(target), IdColumn, pq.QuoteIdentifier(table), quotedColumn,
+	))
+}
+
+func (schemaStore) DropColumnConstraints(ctx context.Context, db DBTX, table string, column string, contype string) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.conname
+		FROM pg_constraint c
+			JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = c.conkey[1]
+		WHERE c.conrelid = $1::regclass
+			AND c.contype = $2::"char"
+			AND cardinality(c.conkey) = 1
+			AND a.attname = $3
+	`, pq.QuoteIdentifier(table), contype, column)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", pq.QuoteIdentifier(table), pq.QuoteIdentifier(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (schemaStore) AddUnique(ctx context.Context, db DBTX, table string, column string, name string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s)",
+		pq.QuoteIdentifier(table), pq.QuoteIdentifier(name), pq.QuoteIdentifier(column)))
+	return err
+}
+
+func (schemaStore) AddCheck(ctx context.Context, db DBTX, table string, name string, expression string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s)",
+		pq.QuoteIdentifier(table), pq.QuoteIdentifier(name), expression))
+	return err
+}
+
+func (schemaStore) AddForeignKey(ctx context.Context, db DBTX, table string, column string, name string, target string, onDelete string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE %s",
+		pq.QuoteIdentifier(table), pq.QuoteIdentifier(name), pq.QuoteIdentifier(column), pq.QuoteIdentifier(target), IdColumn, onDelete))
+	return err
+}
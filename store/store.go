@@ -0,0 +1,82 @@
+// Package store holds the repositories of the metadata tables and of the
+// rows and schema of the user defined tables. Every method takes the
+// connection or transaction it runs on, so a handler can group calls to
+// several repositories in one transaction.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrNotFound is returned when the requested row doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// DBTX is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+type Tx interface {
+	DBTX
+	Commit() error
+	Rollback() error
+}
+
+// Database is the connection pool the repositories run on outside of a
+// transaction.
+type Database interface {
+	DBTX
+	Begin(ctx context.Context) (Tx, error)
+}
+
+type sqlDatabase struct {
+	*sql.DB
+}
+
+func (d sqlDatabase) Begin(ctx context.Context) (Tx, error) {
+	return d.DB.BeginTx(ctx, nil)
+}
+
+// Store groups the repositories with the database they run on.
+type Store struct {
+	DB                Database
+	Tables            TableStore
+	Columns           ColumnStore
+	Indexes           IndexStore
+	ScheduledMessages ScheduledMessageStore
+	Rules             RuleStore
+	Templates         TemplateStore
+	Logs              LogStore
+	Platforms         PlatformStore
+	Data              DataStore
+	Schema            SchemaStore
+}
+
+// New returns the Postgres repositories backed by db.
+func New(db *sql.DB) *Store {
+	return &Store{
+		DB:                sqlDatabase{db},
+		Tables:            tableStore{},
+		Columns:           columnStore{},
+		Indexes:           indexStore{},
+		ScheduledMessages: scheduledMessageStore{},
+		Rules:             ruleStore{},
+		Templates:         templateStore{},
+		Logs:              logStore{},
+		Platforms:         platformStore{},
+		Data:              dataStore{},
+		Schema:            schemaStore{},
+	}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
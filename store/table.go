@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"wolfscream/datatype"
+	"wolfscream/drift"
+	"wolfscream/models"
+	"wolfscream/tablestream"
+
+	"github.com/lib/pq"
+)
+
+// IdColumn is the primary key Create adds to every table. It isn't
+// registered in user_defined_column.
+const IdColumn = "id"
+
+// TableStore creates user defined tables and keeps user_defined_table in
+// step with them.
+type TableStore interface {
+	List(ctx context.Context, db DBTX) ([]models.Table, error)
+	// Get returns ErrNotFound when the table isn't registered.
+	Get(ctx context.Context, db DBTX, name string) (*models.Table, error)
+	// Create creates the table with its id column and change trigger and
+	// registers it.
+	Create(ctx context.Context, db DBTX, name string, description string) error
+	// Rename renames the table and its registration.
+	Rename(ctx context.Context, db DBTX, name string, newName string) error
+	SetDescription(ctx context.Context, db DBTX, name string, description string) error
+	// Drop drops the table with the enum types of its columns and removes
+	// its registration.
+	Drop(ctx context.Context, db DBTX, name string) error
+	// Register registers a table that already exists.
+	Register(ctx context.Context, db DBTX, name string, description *string) error
+	// Unregister removes the registration of a table and its columns
+	// without touching the table.
+	Unregister(ctx context.Context, db DBTX, name string) error
+	// Metadata returns the registered tables with their columns, in the
+	// shape drift compares to the catalog.
+	Metadata(ctx context.Context, db DBTX) ([]drift.Table, error)
+}
+
+type tableStore struct{}
+
+func (tableStore) List(ctx context.Context, db DBTX) ([]models.Table, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, description, created_at FROM user_defined_table ORDER BY created_at ASC;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []models.Table{}
+	for rows.Next() {
+		var table models.Table
+		if err := rows.Scan(&table.Id, &table.Name, &table.Description, &table.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables = append(tables, table)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return tables, nil
+}
+
+func (tableStore) Get(ctx context.Context, db DBTX, name string) (*models.Table, error) {
+	var table models.Table
+	err := db.QueryRowContext(ctx,
+		"SELECT id, name, description, created_at FROM user_defined_table WHERE name = $1",
+		name,
+	).Scan(&table.Id, &table.Name, &table.Description, &table.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &table, nil
+}
+
+func (s tableStore) Create(ctx context.Context, db DBTX, name string, description string) error {
+	query := fmt.Sprintf(`CREATE TABLE %s (%s BIGSERIAL PRIMARY KEY);`, pq.QuoteIdentifier(name), IdColumn)
+
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+
+	if err := tablestream.InstallTrigger(ctx, db, name); err != nil {
+		return err
+	}
+
+	if err := s.Register(ctx, db, name, &description); err != nil {
+		return fmt.Errorf("failed to insert table: %w", err)
+	}
+
+	return nil
+}
+
+func (tableStore) Rename(ctx context.Context, db DBTX, name string, newName string) error {
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s RENAME TO %s;`, pq.QuoteIdentifier(name), pq.QuoteIdentifier(newName))); err != nil {
+		return fmt.Errorf("failed to rename table: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE user_defined_table SET name = $1 WHERE name = $2;`, newName, name); err != nil {
+		return fmt.Errorf("failed to update table: %w", err)
+	}
+
+	return nil
+}
+
+func (tableStore) SetDescription(ctx context.Context, db DBTX, name string, description string) error {
+	if _, err := db.ExecContext(ctx, `UPDATE user_defined_table SET description = $1 WHERE name = $2;`, description, name); err != nil {
+		return fmt.Errorf("failed to update table: %w", err)
+	}
+	return nil
+}
+
+func (s tableStore) Drop(ctx context.Context, db DBTX, name string) error {
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP TABLE %s", pq.QuoteIdentifier(name))); err != nil {
+		return fmt.Errorf("failed to drop table: %w", err)
+	}
+
+	// enum columns own a type that doesn't go away with the table
+	var enumTypes []string
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(array_agg(udc.enum_type), '{}')
+		FROM user_defined_column udc
+			JOIN user_defined_table udt ON udc.user_defined_table_id = udt.id
+		WHERE udt.name = $1 AND udc.enum_type IS NOT NULL
+	`, name).Scan((*pq.StringArray)(&enumTypes)); err != nil {
+		return fmt.Errorf("failed to query enum types: %w", err)
+	}
+
+	for _, enumType := range enumTypes {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP TYPE IF EXISTS %s", pq.QuoteIdentifier(enumType))); err != nil {
+			return fmt.Errorf("failed to drop enum type: %w", err)
+		}
+	}
+
+	if err := s.Unregister(ctx, db, name); err != nil {
+		return fmt.Errorf("failed to delete metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (tableStore) Register(ctx context.Context, db DBTX, name string, description *string) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO user_defined_table (name, description) VALUES ($1, $2);`, name, description)
+	return err
+}
+
+func (tableStore) Unregister(ctx context.Context, db DBTX, name string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM user_defined_table WHERE name = $1", name)
+	return err
+}
+
+func (tableStore) Metadata(ctx context.Context, db DBTX) ([]drift.Table, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			udt.name,
+			udc.name,
+			udc.type,
+			udc.length,
+			udc."precision",
+			udc.scale,
+			udc.enum_type,
+			udc.enum_values,
+			udc.is_nullable
+		FROM user_defined_table udt
+			LEFT JOIN user_defined_column udc ON udc.user_defined_table_id = udt.id
+		ORDER BY udt.name, udc.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metadata: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []drift.Table{}
+	for rows.Next() {
+		var (
+			table    string
+			name     sql.NullString
+			typ      sql.NullString
+			enumType sql.NullString
+			nullable sql.NullBool
+			spec     datatype.Spec
+		)
+		if err := rows.Scan(&table, &name, &typ, &spec.Length, &spec.Precision, &spec.Scale, &enumType, (*pq.StringArray)(&spec.EnumValues), &nullable); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+
+		if len(tables) == 0 || tables[len(tables)-1].Name != table {
+			tables = append(tables, drift.Table{Name: table, Columns: []drift.Column{}})
+		}
+		if !name.Valid {
+			continue
+		}
+
+		spec.Type, spec.EnumType = typ.String, enumType.String
+		tables[len(tables)-1].Columns = append(tables[len(tables)-1].Columns, drift.Column{Name: name.String, Spec: spec, Nullable: nullable.Bool})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return tables, nil
+}
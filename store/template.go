@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"wolfscream/models"
+)
+
+type TemplateStore interface {
+	List(ctx context.Context, db DBTX) ([]models.MessageTemplate, error)
+	Create(ctx context.Context, db DBTX, name string, text string) error
+	Delete(ctx context.Context, db DBTX, id string) error
+}
+
+type templateStore struct{}
+
+func (templateStore) List(ctx context.Context, db DBTX) ([]models.MessageTemplate, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, text, description, created_at FROM message_template;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []models.MessageTemplate{}
+	for rows.Next() {
+		var template models.MessageTemplate
+		if err := rows.Scan(&template.Id, &template.Name, &template.Text, &template.Description, &template.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message template: %w", err)
+		}
+		templates = append(templates, template)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return templates, nil
+}
+
+func (templateStore) Create(ctx context.Context, db DBTX, name string, text string) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO message_template(name, text) VALUES ($1, $2);", name, text)
+	return err
+}
+
+func (templateStore) Delete(ctx context.Context, db DBTX, id string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM message_template WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,123 @@
+package tablestream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"wolfscream/models"
+	"wolfscream/websocket"
+
+	"github.com/lib/pq"
+)
+
+const pingInterval = 90 * time.Second
+
+type Listener struct {
+	listener *pq.Listener
+	done     chan struct{}
+}
+
+// Start makes sure every registered table has the notify trigger, then
+// forwards the notifications into the WebSocket hub on table:{name} topics.
+// ctx bounds the trigger setup only. The listener connects with dsn, outside
+// of the pool.
+func Start(ctx context.Context, db *sql.DB, dsn string) (*Listener, error) {
+	if err := EnsureNotifyFunction(ctx, db); err != nil {
+		return nil, err
+	}
+
+	if err := ensureTriggers(ctx, db); err != nil {
+		return nil, err
+	}
+
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("table stream listener failed", "error", err)
+		}
+	})
+
+	if err := listener.Listen(Channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	l := &Listener{
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+	go l.run()
+
+	return l, nil
+}
+
+func (l *Listener) Close() error {
+	close(l.done)
+	return l.listener.Close()
+}
+
+func (l *Listener) run() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.done:
+			return
+
+		case notification := <-l.listener.Notify:
+			// nil is sent after the connection was re-established
+			if notification == nil {
+				continue
+			}
+			forward(notification.Extra)
+
+		case <-ticker.C:
+			go l.listener.Ping()
+		}
+	}
+}
+
+func forward(payload string) {
+	var event models.TableChangeEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		slog.Error("table stream received an invalid payload", "error", err)
+		return
+	}
+
+	// Every instance receives the notification itself, so it must not be
+	// relayed over the hub backplane.
+	event.CreatedAt = time.Now()
+	websocket.GetHub().BroadcastLocal(websocket.TableTopic(event.Table), event)
+}
+
+func ensureTriggers(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM user_defined_table;")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		tables = append(tables, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if err := InstallTrigger(ctx, db, table); err != nil {
+			slog.ErrorContext(ctx, "failed to install table stream trigger", "table", table, "error", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package tablestream
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the NOTIFY channel used by the trigger installed on every user
+// defined table.
+const Channel = "user_defined_table_change"
+
+const triggerName = "user_defined_table_notify"
+
+// NOTIFY payloads are limited to 8000 bytes, rows that don't fit are sent
+// without their data and flagged as truncated.
+const createNotifyFunction = `
+CREATE OR REPLACE FUNCTION notify_user_defined_table_change() RETURNS trigger AS $$
+DECLARE
+	payload TEXT;
+	row_data JSON;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		row_data := row_to_json(OLD);
+	ELSE
+		row_data := row_to_json(NEW);
+	END IF;
+
+	payload := json_build_object('table', TG_TABLE_NAME, 'event', lower(TG_OP), 'row', row_data)::text;
+
+	IF octet_length(payload) > 7900 THEN
+		payload := json_build_object('table', TG_TABLE_NAME, 'event', lower(TG_OP), 'row', NULL, 'truncated', true)::text;
+	END IF;
+
+	PERFORM pg_notify('` + Channel + `', payload);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+`
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// EnsureNotifyFunction creates or updates the trigger function.
+func EnsureNotifyFunction(ctx context.Context, db execer) error {
+	if _, err := db.ExecContext(ctx, createNotifyFunction); err != nil {
+		return fmt.Errorf("failed to create notify function: %w", err)
+	}
+	return nil
+}
+
+// InstallTrigger makes table publish its row changes on Channel.
+func InstallTrigger(ctx context.Context, db execer, table string) error {
+	query := fmt.Sprintf(
+		"CREATE OR REPLACE TRIGGER %s AFTER INSERT OR UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION notify_user_defined_table_change();",
+		triggerName,
+		pq.QuoteIdentifier(table),
+	)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to install trigger on %s: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"wolfscream/middlewares"
+)
+
+var (
+	ErrNotAuthenticated = NewError(ErrCodeUnauthorized, "not authenticated")
+	ErrInvalidToken     = NewError(ErrCodeUnauthorized, "invalid ID token")
+	ErrTopicForbidden   = NewError(ErrCodeForbidden, "not allowed to subscribe to this topic")
+)
+
+type AuthPayload struct {
+	Token string `json:"token"`
+}
+
+// TopicAuthorizer decides whether a client may subscribe to a topic. It
+// returns nil when the subscription is allowed.
+type TopicAuthorizer func(c *Client, topic string) error
+
+var (
+	authorizersMu sync.RWMutex
+	authorizers   = map[string]TopicAuthorizer{}
+)
+
+// RegisterTopicAuthorizer registers the authorizer used for every topic that
+// starts with prefix. Topics without a matching authorizer are rejected.
+func RegisterTopicAuthorizer(prefix string, authorizer TopicAuthorizer) {
+	authorizersMu.Lock()
+	defer authorizersMu.Unlock()
+	authorizers[prefix] = authorizer
+}
+
+// AuthorizeTopic runs the authorizer registered for the longest prefix
+// matching topic.
+func AuthorizeTopic(c *Client, topic string) error {
+	if !c.Authenticated() {
+		return ErrNotAuthenticated
+	}
+
+	authorizersMu.RLock()
+	var (
+		match      TopicAuthorizer
+		matchedLen = -1
+	)
+	for prefix, authorizer := range authorizers {
+		if strings.HasPrefix(topic, prefix) && len(prefix) > matchedLen {
+			match, matchedLen = authorizer, len(prefix)
+		}
+	}
+	authorizersMu.RUnlock()
+
+	if match == nil {
+		return ErrTopicForbidden
+	}
+	return match(c, topic)
+}
+
+// authenticate handles the "auth" message a client has to send first when it
+// did not pass a token in the query string.
+func (client *Client) authenticate(payload json.RawMessage) (any, error) {
+	var data AuthPayload
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, NewError(ErrCodeInvalidPayload, "payload must contain a token")
+	}
+
+	uid, err := middlewares.VerifyToken(client.Context(), data.Token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	client.UID = uid
+	return map[string]string{"uid": uid}, nil
+}
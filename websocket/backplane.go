@@ -0,0 +1,110 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+)
+
+// Backplane fans broadcasts out to the hubs of every running instance.
+// Implementations deliver each published envelope to every subscriber,
+// including the publishing instance itself.
+type Backplane interface {
+	Publish(envelope []byte) error
+	Subscribe(deliver func(envelope []byte)) error
+	Close() error
+}
+
+// Envelope is what travels over the backplane.
+type Envelope struct {
+	Id     string          `json:"id"`
+	Origin string          `json:"origin"`
+	Topic  string          `json:"topic"`
+	Data   json.RawMessage `json:"data"`
+}
+
+func newId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+const seenCapacity = 4096
+
+// seenSet remembers the ids of the most recent envelopes so an envelope that
+// arrives more than once, or that comes back to the instance which already
+// delivered it locally, is only delivered once.
+type seenSet struct {
+	mu    sync.Mutex
+	ids   map[string]bool
+	order []string
+	next  int
+}
+
+func newSeenSet() *seenSet {
+	return &seenSet{
+		ids:   make(map[string]bool, seenCapacity),
+		order: make([]string, seenCapacity),
+	}
+}
+
+// add records id and reports whether it was new.
+func (s *seenSet) add(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ids[id] {
+		return false
+	}
+
+	if old := s.order[s.next]; old != "" {
+		delete(s.ids, old)
+	}
+	s.order[s.next] = id
+	s.next = (s.next + 1) % seenCapacity
+	s.ids[id] = true
+	return true
+}
+
+// MemoryBus connects the hubs of a single process, it stands in for a real
+// backplane in tests.
+type MemoryBus struct {
+	mu          sync.RWMutex
+	subscribers []func(envelope []byte)
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{}
+}
+
+// Backplane returns a new backplane attached to the bus.
+func (bus *MemoryBus) Backplane() Backplane {
+	return &memoryBackplane{bus: bus}
+}
+
+type memoryBackplane struct {
+	bus *MemoryBus
+}
+
+func (b *memoryBackplane) Publish(envelope []byte) error {
+	b.bus.mu.RLock()
+	subscribers := append([]func([]byte){}, b.bus.subscribers...)
+	b.bus.mu.RUnlock()
+
+	for _, deliver := range subscribers {
+		deliver(envelope)
+	}
+	return nil
+}
+
+func (b *memoryBackplane) Subscribe(deliver func(envelope []byte)) error {
+	b.bus.mu.Lock()
+	defer b.bus.mu.Unlock()
+	b.bus.subscribers = append(b.bus.subscribers, deliver)
+	return nil
+}
+
+func (b *memoryBackplane) Close() error {
+	return nil
+}
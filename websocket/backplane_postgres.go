@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	BackplaneChannel = "websocket_hub"
+
+	// NOTIFY payloads are limited to 8000 bytes.
+	maxNotifyPayload = 7900
+
+	// publishTimeout keeps a slow database from holding up the hub.
+	publishTimeout = 5 * time.Second
+)
+
+var ErrEnvelopeTooLarge = errors.New("envelope too large for NOTIFY")
+
+// PostgresBackplane relays envelopes between instances with LISTEN/NOTIFY.
+type PostgresBackplane struct {
+	db       *sql.DB
+	listener *pq.Listener
+	done     chan struct{}
+}
+
+func NewPostgresBackplane(db *sql.DB, dsn string) *PostgresBackplane {
+	return &PostgresBackplane{
+		db: db,
+		listener: pq.NewListener(dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				slog.Warn("hub backplane listener failed", "error", err)
+			}
+		}),
+		done: make(chan struct{}),
+	}
+}
+
+// Publish notifies every instance. Envelopes that don't fit in a NOTIFY
+// payload are rejected and only reach the clients of this instance.
+func (b *PostgresBackplane) Publish(envelope []byte) error {
+	if len(envelope) > maxNotifyPayload {
+		return ErrEnvelopeTooLarge
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	_, err := b.db.ExecContext(ctx, "SELECT pg_notify($1, $2);", BackplaneChannel, string(envelope))
+	return err
+}
+
+func (b *PostgresBackplane) Subscribe(deliver func(envelope []byte)) error {
+	if err := b.listener.Listen(BackplaneChannel); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(90 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-b.done:
+				return
+
+			case notification := <-b.listener.Notify:
+				// nil is sent after the connection was re-established
+				if notification == nil {
+					continue
+				}
+				deliver([]byte(notification.Extra))
+
+			case <-ticker.C:
+				go b.listener.Ping()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (b *PostgresBackplane) Close() error {
+	close(b.done)
+	return b.listener.Close()
+}
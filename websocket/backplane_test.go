@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func TestBroadcastReachesEveryInstance(t *testing.T) {
+	bus := NewMemoryBus()
+
+	a, b := NewHub(), NewHub()
+	for _, h := range []*Hub{a, b} {
+		if err := h.UseBackplane(bus.Backplane()); err != nil {
+			t.Fatal(err)
+		}
+		go h.Run()
+	}
+
+	clientA, clientB := newTestClient(a), newTestClient(b)
+	a.register <- clientA
+	b.register <- clientB
+	a.Subscribe(clientA, "topic:a")
+	b.Subscribe(clientB, "topic:a")
+
+	a.Broadcast("topic:a", "hello")
+
+	for name, c := range map[string]*Client{"a": clientA, "b": clientB} {
+		if got := len(c.Send); got != 1 {
+			t.Fatalf("client on hub %s received %d messages, want 1", name, got)
+		}
+
+		var msg Message
+		if err := json.Unmarshal(<-c.Send, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Topic != "topic:a" || msg.Data != "hello" {
+			t.Fatalf("client on hub %s received %+v", name, msg)
+		}
+	}
+}
+
+func TestBroadcastLocalStaysOnInstance(t *testing.T) {
+	bus := NewMemoryBus()
+
+	a, b := NewHub(), NewHub()
+	for _, h := range []*Hub{a, b} {
+		if err := h.UseBackplane(bus.Backplane()); err != nil {
+			t.Fatal(err)
+		}
+		go h.Run()
+	}
+
+	clientB := newTestClient(b)
+	b.register <- clientB
+	b.Subscribe(clientB, "topic:a")
+
+	a.BroadcastLocal("topic:a", "hello")
+
+	if got := len(clientB.Send); got != 0 {
+		t.Fatalf("client on other instance received %d messages, want 0", got)
+	}
+}
+
+func TestDuplicateEnvelopeIsDeliveredOnce(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+
+	c := newTestClient(h)
+	h.register <- c
+	h.Subscribe(c, "topic:a")
+
+	payload, _ := json.Marshal(Envelope{Id: "1", Origin: "other", Topic: "topic:a", Data: json.RawMessage(`"hello"`)})
+	h.receive(payload)
+	h.receive(payload)
+
+	if got := len(c.Send); got != 1 {
+		t.Fatalf("received %d messages, want 1", got)
+	}
+}
+
+func TestSeenSetEvictsOldest(t *testing.T) {
+	s := newSeenSet()
+	for i := 0; i < seenCapacity+1; i++ {
+		s.add(strconv.Itoa(i))
+	}
+
+	if !s.add(strconv.Itoa(0)) {
+		t.Fatal("expected the oldest id to have been evicted")
+	}
+	if s.add(strconv.Itoa(seenCapacity)) {
+		t.Fatal("expected the newest id to still be remembered")
+	}
+}
@@ -1,7 +1,9 @@
 package websocket
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"log/slog"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -11,6 +13,7 @@ const (
 	writeWait  = 10 * time.Second
 	pongWait   = 60 * time.Second
 	pingPeriod = pongWait * 9 / 10
+	authWait   = 10 * time.Second
 )
 
 type Client struct {
@@ -18,36 +21,96 @@ type Client struct {
 	Send   chan []byte
 	Topics map[string]bool
 	Hub    *Hub
+	UID    string
+
+	// ctx is canceled when the read loop ends, so queries run for the
+	// client stop with the connection.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func NewClient(conn *websocket.Conn, hub *Hub) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Client{
 		Conn:   conn,
 		Send:   make(chan []byte, 256),
 		Topics: make(map[string]bool),
 		Hub:    hub,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Context returns the context of the connection.
+func (client *Client) Context() context.Context {
+	if client.ctx == nil {
+		return context.Background()
 	}
+	return client.ctx
+}
+
+func (client *Client) Authenticated() bool {
+	return client.UID != ""
+}
+
+// SendMessage queues msg for the write loop.
+func (client *Client) SendMessage(msg Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("failed to marshal message", "type", msg.Type, "error", err)
+		return
+	}
+	client.Send <- data
 }
 
 func (client *Client) ReadLoop() {
+	// Unregistering closes Send, the write loop then flushes the pending
+	// replies and closes the connection.
 	defer func() {
+		if client.cancel != nil {
+			client.cancel()
+		}
 		client.Hub.unregister <- client
-		client.Conn.Close()
 	}()
 
 	client.Conn.SetReadLimit(5120)
-	client.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	if client.Authenticated() {
+		client.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	} else {
+		client.Conn.SetReadDeadline(time.Now().Add(authWait))
+	}
 	client.Conn.SetPongHandler(func(string) error {
 		client.Conn.SetReadDeadline(time.Now().Add(pongWait))
 		return nil
 	})
 
 	for {
-		var msg Message
-		if err := client.Conn.ReadJSON(&msg); err != nil {
-			log.Println("read error:", err)
+		_, data, err := client.Conn.ReadMessage()
+		if err != nil {
+			slog.Info("websocket read failed", "client", client.UID, "error", err)
 			break
 		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			client.reply(msg, nil, NewError(ErrCodeInvalidPayload, "message must be a JSON object"))
+			continue
+		}
+
+		if !client.Authenticated() {
+			if msg.Type != "auth" {
+				client.reply(msg, nil, ErrNotAuthenticated)
+				break
+			}
+			data, err := client.authenticate(msg.Payload)
+			client.reply(msg, data, err)
+			if err != nil {
+				break
+			}
+			client.Conn.SetReadDeadline(time.Now().Add(pongWait))
+			continue
+		}
+
 		Dispatch(client, msg)
 	}
 }
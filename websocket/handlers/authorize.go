@@ -0,0 +1,22 @@
+package websocket_handlers
+
+import (
+	"wolfscream/websocket"
+)
+
+// Scheduled messages and tables have no owner yet and tokens carry no scopes,
+// VerifyToken returns the same identity for every token. There is nothing to
+// check a client against, so the authorizers deny every subscription until
+// topics can be matched to the users allowed to see them.
+
+// AuthorizeScheduledMessage decides whether a client may subscribe to
+// scheduled-message:{name}, the logs of a scheduled message.
+func (h *Handlers) AuthorizeScheduledMessage(c *websocket.Client, topic string) error {
+	return websocket.ErrTopicForbidden
+}
+
+// AuthorizeTable decides whether a client may subscribe to table:{name}, the
+// row changes of a table.
+func (h *Handlers) AuthorizeTable(c *websocket.Client, topic string) error {
+	return websocket.ErrTopicForbidden
+}
@@ -1,9 +1,24 @@
 package websocket_handlers
 
 import (
+	"database/sql"
+
+	"wolfscream/scheduler"
 	"wolfscream/websocket"
 )
 
-func InitHandlers() {
-	websocket.RegisterHandler("subscribe", Subscribe)
+// Handlers authorizes the topics of the hub and loads their snapshots.
+type Handlers struct {
+	db        *sql.DB
+	scheduler *scheduler.Scheduler
+}
+
+func InitHandlers(db *sql.DB, sched *scheduler.Scheduler) {
+	h := &Handlers{db: db, scheduler: sched}
+
+	websocket.RegisterTopicAuthorizer(websocket.ScheduledMessageTopicPrefix, h.AuthorizeScheduledMessage)
+	websocket.RegisterTopicAuthorizer(websocket.TableTopicPrefix, h.AuthorizeTable)
+
+	websocket.RegisterSnapshot(websocket.ScheduledMessageTopicPrefix, h.ScheduledMessageSnapshot)
+	websocket.RegisterSnapshot(websocket.TableTopicPrefix, h.TableSnapshot)
 }
@@ -0,0 +1,114 @@
+package websocket_handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"wolfscream/models"
+	"wolfscream/websocket"
+)
+
+const snapshotLogLimit = 20
+
+type ScheduledMessageState struct {
+	State   string       `json:"state"`
+	PrevRun *time.Time   `json:"prev_run"`
+	NextRun *time.Time   `json:"next_run"`
+	Logs    []models.Log `json:"logs"`
+}
+
+// ScheduledMessageSnapshot returns the current state of a scheduled message
+// and its latest logs, oldest first.
+func (h *Handlers) ScheduledMessageSnapshot(ctx context.Context, topic string) (any, error) {
+	name := websocket.TopicName(topic, websocket.ScheduledMessageTopicPrefix)
+
+	var (
+		scheduledMessageId int
+		runningId          *int
+	)
+	err := h.db.QueryRowContext(ctx, `
+		SELECT
+			sm.id,
+			rsm.id
+		FROM scheduled_message sm
+		LEFT JOIN running_scheduled_message rsm ON sm.id = rsm.scheduled_message_id
+		WHERE sm.name = $1
+	`, name).Scan(&scheduledMessageId, &runningId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled message: %w", err)
+	}
+
+	state := ScheduledMessageState{State: "stopped", Logs: []models.Log{}}
+	if runningId != nil {
+		state.State = "started"
+		state.PrevRun, state.NextRun = h.scheduler.Runs(*runningId)
+	}
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT id, text, level, created_at, run_id FROM (
+			SELECT id, text, level, created_at, run_id
+			FROM scheduled_message_log
+			WHERE scheduled_message_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		) latest
+		ORDER BY created_at ASC
+	`, scheduledMessageId, snapshotLogLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var log models.Log
+		if err := rows.Scan(&log.Id, &log.Text, &log.Level, &log.CreatedAt, &log.RunId); err != nil {
+			return nil, fmt.Errorf("failed to scan log: %w", err)
+		}
+		state.Logs = append(state.Logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return state, nil
+}
+
+type TableState struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+}
+
+// TableSnapshot returns the registered columns of the table so the client
+// knows the shape of the rows carried by the change events.
+func (h *Handlers) TableSnapshot(ctx context.Context, topic string) (any, error) {
+	name := websocket.TopicName(topic, websocket.TableTopicPrefix)
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT udc.name
+		FROM user_defined_column udc
+			JOIN user_defined_table udt ON udc.user_defined_table_id = udt.id
+		WHERE udt.name = $1
+		ORDER BY udc.id ASC
+	`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer rows.Close()
+
+	state := TableState{Table: name, Columns: []string{}}
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		state.Columns = append(state.Columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return state, nil
+}
@@ -1,13 +1,31 @@
 package websocket
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"sync"
+)
 
+// Hub keeps track of the connected clients and their subscriptions. Clients
+// are registered and unregistered by the Run loop, subscriptions change from
+// the client read loops and broadcasts come from anywhere, so all of the
+// state is guarded by mu.
+//
+// With a backplane, broadcasts reach the clients of every instance. The hub
+// delivers to its own clients right away and drops the copy that comes back
+// over the backplane.
 type Hub struct {
+	mu      sync.RWMutex
 	clients map[*Client]bool
 	topics  map[string]map[*Client]bool
 
 	register   chan *Client
 	unregister chan *Client
+
+	instanceId string
+	backplane  Backplane
+	seen       *seenSet
 }
 
 var hub *Hub
@@ -18,12 +36,29 @@ func NewHub() *Hub {
 		topics:     make(map[string]map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		instanceId: newId(),
+		seen:       newSeenSet(),
 	}
 }
 
-func InitHub() {
+// InitHub starts the process wide hub. backplane may be nil when the
+// instance runs alone.
+func InitHub(backplane Backplane) error {
 	hub = NewHub()
+	if backplane != nil {
+		if err := hub.UseBackplane(backplane); err != nil {
+			return err
+		}
+	}
 	go hub.Run()
+	return nil
+}
+
+// UseBackplane connects the hub to other instances. It must be called before
+// the hub is used.
+func (h *Hub) UseBackplane(backplane Backplane) error {
+	h.backplane = backplane
+	return backplane.Subscribe(h.receive)
 }
 
 func GetHub() *Hub {
@@ -37,19 +72,28 @@ func (h *Hub) Run() {
 	for {
 		select {
 		case c := <-h.register:
+			h.mu.Lock()
 			h.clients[c] = true
+			h.mu.Unlock()
 
 		case c := <-h.unregister:
-			delete(h.clients, c)
-			for topic := range c.Topics {
-				delete(h.topics[topic], c)
+			h.mu.Lock()
+			if h.clients[c] {
+				delete(h.clients, c)
+				for topic := range c.Topics {
+					h.removeFromTopic(c, topic)
+				}
+				close(c.Send)
 			}
-			close(c.Send)
+			h.mu.Unlock()
 		}
 	}
 }
 
 func (h *Hub) Subscribe(c *Client, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	if h.topics[topic] == nil {
 		h.topics[topic] = make(map[*Client]bool)
 	}
@@ -57,18 +101,114 @@ func (h *Hub) Subscribe(c *Client, topic string) {
 	c.Topics[topic] = true
 }
 
-func (h *Hub) Unsubscribe(c *Client, topic string) {
-	delete(h.topics[topic], c)
+// Unsubscribe removes the client from topic and reports whether it was
+// subscribed.
+func (h *Hub) Unsubscribe(c *Client, topic string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !c.Topics[topic] {
+		return false
+	}
+	h.removeFromTopic(c, topic)
 	delete(c.Topics, topic)
+	return true
+}
+
+func (h *Hub) removeFromTopic(c *Client, topic string) {
+	delete(h.topics[topic], c)
+	if len(h.topics[topic]) == 0 {
+		delete(h.topics, topic)
+	}
+}
+
+// Topics returns the topics the client is subscribed to, sorted.
+func (h *Hub) Topics(c *Client) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	topics := make([]string, 0, len(c.Topics))
+	for topic := range c.Topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Stats returns the number of connected clients and of subscriptions, a
+// client subscribed to two topics counting twice.
+func (h *Hub) Stats() (clients int, subscriptions int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, subscribers := range h.topics {
+		subscriptions += len(subscribers)
+	}
+	return len(h.clients), subscriptions
 }
 
+// Broadcast sends data to the subscribers of topic on every instance.
 func (h *Hub) Broadcast(topic string, data any) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		slog.Error("failed to marshal broadcast", "topic", topic, "error", err)
+		return
+	}
+
+	envelope := Envelope{
+		Id:     newId(),
+		Origin: h.instanceId,
+		Topic:  topic,
+		Data:   raw,
+	}
+
+	h.seen.add(envelope.Id)
+	h.deliver(topic, raw)
+
+	if h.backplane == nil {
+		return
+	}
+
+	payload, _ := json.Marshal(envelope)
+	if err := h.backplane.Publish(payload); err != nil {
+		slog.Warn("broadcast only reached local clients", "topic", topic, "error", err)
+	}
+}
+
+// BroadcastLocal sends data to the subscribers of topic on this instance
+// only, for events that every instance observes by itself.
+func (h *Hub) BroadcastLocal(topic string, data any) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		slog.Error("failed to marshal broadcast", "topic", topic, "error", err)
+		return
+	}
+	h.deliver(topic, raw)
+}
+
+func (h *Hub) receive(payload []byte) {
+	var envelope Envelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		slog.Error("hub backplane received an invalid envelope", "error", err)
+		return
+	}
+
+	if !h.seen.add(envelope.Id) {
+		return
+	}
+	h.deliver(envelope.Topic, envelope.Data)
+}
+
+func (h *Hub) deliver(topic string, data json.RawMessage) {
 	msg, _ := json.Marshal(Message{
 		Type:  "event",
 		Topic: topic,
 		Data:  data,
 	})
 
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
 	for c := range h.topics[topic] {
 		select {
 		case c.Send <- msg:
@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func newTestClient(h *Hub) *Client {
+	return &Client{
+		Send:   make(chan []byte, 256),
+		Topics: make(map[string]bool),
+		Hub:    h,
+		UID:    "test",
+	}
+}
+
+func TestHubSubscribeAndBroadcast(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+
+	c := newTestClient(h)
+	h.register <- c
+
+	h.Subscribe(c, "topic:a")
+	h.Broadcast("topic:a", map[string]int{"n": 1})
+	h.Broadcast("topic:b", map[string]int{"n": 2})
+
+	var msg Message
+	if err := json.Unmarshal(<-c.Send, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "event" || msg.Topic != "topic:a" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	select {
+	case data := <-c.Send:
+		t.Fatalf("received message for a topic that is not subscribed: %s", data)
+	default:
+	}
+
+	if !h.Unsubscribe(c, "topic:a") {
+		t.Fatal("expected client to be subscribed")
+	}
+	if h.Unsubscribe(c, "topic:a") {
+		t.Fatal("expected second unsubscribe to report not subscribed")
+	}
+}
+
+func TestHubUnregisterClosesSend(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+
+	c := newTestClient(h)
+	h.register <- c
+	h.Subscribe(c, "topic:a")
+	h.unregister <- c
+
+	for range c.Send {
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.topics) != 0 || len(h.clients) != 0 {
+		t.Fatalf("hub still references client: %d topics, %d clients", len(h.topics), len(h.clients))
+	}
+}
+
+// TestHubConcurrentAccess is meant to be run with -race.
+func TestHubConcurrentAccess(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			c := newTestClient(h)
+			h.register <- c
+
+			topic := fmt.Sprintf("topic:%d", i%3)
+			for j := 0; j < 100; j++ {
+				h.Subscribe(c, topic)
+				h.Broadcast(topic, j)
+				h.Topics(c)
+				h.Unsubscribe(c, topic)
+			}
+
+			h.Subscribe(c, topic)
+			h.unregister <- c
+		}(i)
+	}
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				h.Broadcast(fmt.Sprintf("topic:%d", j%3), j)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestHubStats(t *testing.T) {
+	h := NewHub()
+
+	a, b := newTestClient(h), newTestClient(h)
+	h.clients[a] = true
+	h.clients[b] = true
+
+	h.Subscribe(a, "topic:a")
+	h.Subscribe(a, "topic:b")
+	h.Subscribe(b, "topic:a")
+
+	clients, subscriptions := h.Stats()
+	if clients != 2 || subscriptions != 3 {
+		t.Errorf("Stats() = %d clients, %d subscriptions, want 2 and 3", clients, subscriptions)
+	}
+}
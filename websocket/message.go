@@ -2,9 +2,37 @@ package websocket
 
 import "encoding/json"
 
+// Message is the envelope for everything sent over the socket. Requests from
+// the client carry an optional Id which is echoed in the "ack" or "error"
+// reply.
 type Message struct {
 	Type    string          `json:"type"`
+	Id      string          `json:"id,omitempty"`
 	Payload json.RawMessage `json:"payload,omitempty"`
 	Topic   string          `json:"topic,omitempty"`
 	Data    any             `json:"data,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+const (
+	ErrCodeUnauthorized         = "unauthorized"
+	ErrCodeAlreadyAuthenticated = "already_authenticated"
+	ErrCodeForbidden            = "forbidden"
+	ErrCodeInvalidPayload       = "invalid_payload"
+	ErrCodeUnknownType          = "unknown_type"
+	ErrCodeNotSubscribed        = "not_subscribed"
+	ErrCodeInternal             = "internal_error"
+)
+
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NewError(code string, message string) *Error {
+	return &Error{Code: code, Message: message}
 }
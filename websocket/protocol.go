@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+type TopicPayload struct {
+	Topic string `json:"topic"`
+}
+
+func init() {
+	RegisterHandler("auth", handleAuth)
+	RegisterHandler("subscribe", handleSubscribe)
+	RegisterHandler("unsubscribe", handleUnsubscribe)
+	RegisterHandler("ping", handlePing)
+	RegisterHandler("list_subscriptions", handleListSubscriptions)
+}
+
+func decodeTopic(payload json.RawMessage) (string, error) {
+	var data TopicPayload
+	if err := json.Unmarshal(payload, &data); err != nil || data.Topic == "" {
+		return "", NewError(ErrCodeInvalidPayload, "payload must contain a topic")
+	}
+	return data.Topic, nil
+}
+
+// handleAuth only runs for clients that are already authenticated, the first
+// "auth" message is handled by the read loop.
+func handleAuth(c *Client, payload json.RawMessage) (any, error) {
+	return nil, NewError(ErrCodeAlreadyAuthenticated, "already authenticated")
+}
+
+// handleSubscribe authorizes the topic, subscribes the client and sends the
+// initial snapshot registered for the topic, if any. The client subscribes
+// before the snapshot is loaded so it doesn't miss the events in between,
+// and is unsubscribed again if the snapshot fails.
+func handleSubscribe(c *Client, payload json.RawMessage) (any, error) {
+	topic, err := decodeTopic(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := AuthorizeTopic(c, topic); err != nil {
+		slog.WarnContext(c.Context(), "subscription denied", "topic", topic, "client", c.UID, "error", err)
+		return nil, err
+	}
+
+	subscribed := slices.Contains(c.Hub.Topics(c), topic)
+	c.Hub.Subscribe(c, topic)
+
+	state, err := Snapshot(c.Context(), topic)
+	if err != nil {
+		if !subscribed {
+			c.Hub.Unsubscribe(c, topic)
+		}
+		slog.ErrorContext(c.Context(), "failed to load snapshot", "topic", topic, "error", err)
+		return nil, NewError(ErrCodeInternal, "failed to load snapshot")
+	}
+
+	if state != nil {
+		c.SendMessage(Message{
+			Type:  "snapshot",
+			Topic: topic,
+			Data:  state,
+		})
+	}
+
+	return TopicPayload{Topic: topic}, nil
+}
+
+func handleUnsubscribe(c *Client, payload json.RawMessage) (any, error) {
+	topic, err := decodeTopic(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.Hub.Unsubscribe(c, topic) {
+		return nil, NewError(ErrCodeNotSubscribed, "not subscribed to "+topic)
+	}
+
+	return TopicPayload{Topic: topic}, nil
+}
+
+func handlePing(c *Client, payload json.RawMessage) (any, error) {
+	return map[string]any{"time": time.Now()}, nil
+}
+
+func handleListSubscriptions(c *Client, payload json.RawMessage) (any, error) {
+	return map[string]any{"topics": c.Hub.Topics(c)}, nil
+}
@@ -0,0 +1,174 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestMain(m *testing.M) {
+	InitHub(nil)
+
+	RegisterTopicAuthorizer("test:", func(c *Client, topic string) error {
+		if topic == "test:forbidden" {
+			return ErrTopicForbidden
+		}
+		return nil
+	})
+	RegisterSnapshot("test:", func(ctx context.Context, topic string) (any, error) {
+		if topic == "test:broken" {
+			return nil, errors.New("snapshot failed")
+		}
+		return map[string]string{"topic": topic}, nil
+	})
+
+	os.Exit(m.Run())
+}
+
+func dial(t *testing.T, query string, header http.Header) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(HandleWebSocket))
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + query
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dial failed with status %d: %v", status, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func request(t *testing.T, conn *websocket.Conn, msg map[string]any) Message {
+	t.Helper()
+
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatal(err)
+	}
+	return read(t, conn)
+}
+
+func read(t *testing.T, conn *websocket.Conn) Message {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var reply Message
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func expectAck(t *testing.T, reply Message, id string) {
+	t.Helper()
+	if reply.Type != "ack" || reply.Id != id {
+		t.Fatalf("expected ack for %q, got %+v", id, reply)
+	}
+}
+
+func expectError(t *testing.T, reply Message, id string, code string) {
+	t.Helper()
+	if reply.Type != "error" || reply.Id != id || reply.Error == nil || reply.Error.Code != code {
+		t.Fatalf("expected %s error for %q, got %+v", code, id, reply)
+	}
+}
+
+func TestProtocol(t *testing.T) {
+	conn := dial(t, "?token=secret", nil)
+
+	expectAck(t, request(t, conn, map[string]any{"type": "ping", "id": "1"}), "1")
+
+	expectError(t, request(t, conn, map[string]any{"type": "nope", "id": "2"}), "2", ErrCodeUnknownType)
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, read(t, conn), "", ErrCodeInvalidPayload)
+
+	expectError(t, request(t, conn, map[string]any{"type": "subscribe", "id": "3", "payload": "x"}), "3", ErrCodeInvalidPayload)
+	expectError(t, request(t, conn, map[string]any{"type": "subscribe", "id": "4", "payload": map[string]string{"topic": "test:forbidden"}}), "4", ErrCodeForbidden)
+	expectError(t, request(t, conn, map[string]any{"type": "subscribe", "id": "5", "payload": map[string]string{"topic": "other:a"}}), "5", ErrCodeForbidden)
+
+	snapshot := request(t, conn, map[string]any{"type": "subscribe", "id": "6", "payload": map[string]string{"topic": "test:a"}})
+	if snapshot.Type != "snapshot" || snapshot.Topic != "test:a" {
+		t.Fatalf("expected snapshot, got %+v", snapshot)
+	}
+	expectAck(t, read(t, conn), "6")
+
+	list := request(t, conn, map[string]any{"type": "list_subscriptions", "id": "7"})
+	expectAck(t, list, "7")
+	topics := list.Data.(map[string]any)["topics"].([]any)
+	if len(topics) != 1 || topics[0] != "test:a" {
+		t.Fatalf("unexpected subscriptions %v", topics)
+	}
+
+	GetHub().Broadcast("test:a", "hello")
+	event := read(t, conn)
+	if event.Type != "event" || event.Topic != "test:a" || event.Data != "hello" {
+		t.Fatalf("expected event, got %+v", event)
+	}
+
+	expectAck(t, request(t, conn, map[string]any{"type": "unsubscribe", "id": "8", "payload": map[string]string{"topic": "test:a"}}), "8")
+	expectError(t, request(t, conn, map[string]any{"type": "unsubscribe", "id": "9", "payload": map[string]string{"topic": "test:a"}}), "9", ErrCodeNotSubscribed)
+	expectError(t, request(t, conn, map[string]any{"type": "auth", "id": "10", "payload": map[string]string{"token": "secret"}}), "10", ErrCodeAlreadyAuthenticated)
+}
+
+func TestFailedSnapshotDoesNotSubscribe(t *testing.T) {
+	conn := dial(t, "?token=secret", nil)
+
+	expectError(t, request(t, conn, map[string]any{"type": "subscribe", "id": "1", "payload": map[string]string{"topic": "test:broken"}}), "1", ErrCodeInternal)
+
+	list := request(t, conn, map[string]any{"type": "list_subscriptions", "id": "2"})
+	expectAck(t, list, "2")
+	if topics := list.Data.(map[string]any)["topics"].([]any); len(topics) != 0 {
+		t.Fatalf("subscribed to %v after a failed snapshot", topics)
+	}
+
+	GetHub().Broadcast("test:broken", "hello")
+	expectAck(t, request(t, conn, map[string]any{"type": "ping", "id": "3"}), "3")
+}
+
+func TestAuthMessage(t *testing.T) {
+	conn := dial(t, "", nil)
+
+	expectAck(t, request(t, conn, map[string]any{"type": "auth", "id": "1", "payload": map[string]string{"token": "secret"}}), "1")
+	expectAck(t, request(t, conn, map[string]any{"type": "ping", "id": "2"}), "2")
+}
+
+func TestUnauthenticatedClientIsRejected(t *testing.T) {
+	conn := dial(t, "", nil)
+
+	expectError(t, request(t, conn, map[string]any{"type": "ping", "id": "1"}), "1", ErrCodeUnauthorized)
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("expected the connection to be closed")
+	}
+}
+
+func TestDisallowedOrigin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(HandleWebSocket))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "?token=secret"
+	_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": []string{"http://evil.example"}})
+	if err == nil {
+		t.Fatal("expected handshake to fail")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %v", resp)
+	}
+}
@@ -1,17 +1,52 @@
 package websocket
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
 
-type HandlerFunc func(c *Client, payload json.RawMessage)
+// HandlerFunc handles one message type. The returned value is sent back as the
+// data of the "ack" reply, an error is sent back as an "error" reply.
+type HandlerFunc func(c *Client, payload json.RawMessage) (any, error)
 
-var handlers = map[string]HandlerFunc{}
+var (
+	handlersMu sync.RWMutex
+	handlers   = map[string]HandlerFunc{}
+)
 
 func RegisterHandler(event string, handler HandlerFunc) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
 	handlers[event] = handler
 }
 
 func Dispatch(c *Client, msg Message) {
-	if handler, ok := handlers[msg.Type]; ok {
-		handler(c, msg.Payload)
+	handlersMu.RLock()
+	handler, ok := handlers[msg.Type]
+	handlersMu.RUnlock()
+
+	if !ok {
+		c.reply(msg, nil, NewError(ErrCodeUnknownType, fmt.Sprintf("unknown message type %q", msg.Type)))
+		return
+	}
+
+	data, err := handler(c, msg.Payload)
+	c.reply(msg, data, err)
+}
+
+func (c *Client) reply(request Message, data any, err error) {
+	if err == nil {
+		c.SendMessage(Message{Type: "ack", Id: request.Id, Data: data})
+		return
+	}
+
+	var wsErr *Error
+	if !errors.As(err, &wsErr) {
+		slog.ErrorContext(c.Context(), "websocket handler failed", "type", request.Type, "error", err)
+		wsErr = NewError(ErrCodeInternal, "internal error")
 	}
+	c.SendMessage(Message{Type: "error", Id: request.Id, Error: wsErr})
 }
@@ -0,0 +1,45 @@
+package websocket
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// SnapshotFunc returns the initial state sent to a client right after it
+// subscribed to topic.
+type SnapshotFunc func(ctx context.Context, topic string) (any, error)
+
+var (
+	snapshotsMu sync.RWMutex
+	snapshots   = map[string]SnapshotFunc{}
+)
+
+// RegisterSnapshot registers the snapshot used for every topic that starts
+// with prefix.
+func RegisterSnapshot(prefix string, snapshot SnapshotFunc) {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	snapshots[prefix] = snapshot
+}
+
+// Snapshot runs the snapshot registered for the longest prefix matching
+// topic. It returns nil when there is none.
+func Snapshot(ctx context.Context, topic string) (any, error) {
+	snapshotsMu.RLock()
+	var (
+		match      SnapshotFunc
+		matchedLen = -1
+	)
+	for prefix, snapshot := range snapshots {
+		if strings.HasPrefix(topic, prefix) && len(prefix) > matchedLen {
+			match, matchedLen = snapshot, len(prefix)
+		}
+	}
+	snapshotsMu.RUnlock()
+
+	if match == nil {
+		return nil, nil
+	}
+	return match(ctx, topic)
+}
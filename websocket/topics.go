@@ -0,0 +1,21 @@
+package websocket
+
+import "strings"
+
+const (
+	ScheduledMessageTopicPrefix = "scheduled-message:"
+	TableTopicPrefix            = "table:"
+)
+
+func ScheduledMessageTopic(name string) string {
+	return ScheduledMessageTopicPrefix + name
+}
+
+func TableTopic(name string) string {
+	return TableTopicPrefix + name
+}
+
+// TopicName returns the part of the topic after prefix.
+func TopicName(topic, prefix string) string {
+	return strings.TrimPrefix(topic, prefix)
+}
@@ -3,23 +3,49 @@ package websocket
 import (
 	"net/http"
 
+	"wolfscream/middlewares"
+
 	"github.com/gorilla/websocket"
 )
 
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+	CheckOrigin: checkOrigin,
+}
+
+// checkOrigin only lets browsers connect from the origins allowed by CORS.
+// Requests without an Origin header come from non-browser clients and still
+// have to authenticate with a token.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	return middlewares.IsAllowedOrigin(origin)
 }
 
+// HandleWebSocket upgrades the request and starts the client loops. The token
+// can be passed as the "token" query parameter, otherwise the first message
+// sent by the client must be an "auth" message.
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-		conn, err := upgrader.Upgrade(w, r, nil)
+	uid := ""
+	if token := r.URL.Query().Get("token"); token != "" {
+		verified, err := middlewares.VerifyToken(r.Context(), token)
 		if err != nil {
+			http.Error(w, "Invalid ID token", http.StatusUnauthorized)
 			return
 		}
+		uid = verified
+	}
 
-		client := NewClient(conn, GetHub())
-		GetHub().register <- client
-
-		go client.WriteLoop()
-		go client.ReadLoop()
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
 	}
 
+	client := NewClient(conn, GetHub())
+	client.UID = uid
+	GetHub().register <- client
+
+	go client.WriteLoop()
+	go client.ReadLoop()
+}